@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dvd/cliptui/internal/clipboard"
+	"github.com/dvd/cliptui/internal/config"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+var addFlags struct {
+	typ  string
+	tags []string
+	pin  bool
+	ttl  time.Duration
+	copy bool
+}
+
+var addCmd = &cobra.Command{
+	Use:   "add [text...]",
+	Short: "Add content to the history",
+	Long: `Stores the arguments, joined by spaces, or everything read from stdin as a
+new history item. The system clipboard is left alone unless --copy is given.
+
+Binary input (NUL bytes or invalid UTF-8) is stored as a binary item or
+rejected, depending on the storage.binary setting.`,
+	Example: `  kubectl get pods | cliptui add --tag k8s --copy
+  cliptui add --pin "ssh deploy@10.0.0.5"
+  cliptui add --ttl 10m < token.txt`,
+	Run: func(cmd *cobra.Command, args []string) {
+		addItem(cmd, args)
+	},
+}
+
+func init() {
+	flags := addCmd.Flags()
+	flags.StringVarP(&addFlags.typ, "type", "t", "", "Item type instead of detecting it ("+strings.Join(types.Types, ", ")+")")
+	flags.StringSliceVar(&addFlags.tags, "tag", nil, "Tag the item (repeatable)")
+	flags.BoolVar(&addFlags.pin, "pin", false, "Pin the item so retention never removes it")
+	flags.DurationVar(&addFlags.ttl, "ttl", 0, "Remove the item after this duration (e.g. 10m)")
+	flags.BoolVar(&addFlags.copy, "copy", false, "Also put the content on the system clipboard")
+}
+
+func addItem(cmd *cobra.Command, args []string) {
+	content, err := readContent(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read content: %v\n", err)
+		os.Exit(1)
+	}
+	if content == "" {
+		fmt.Fprintln(os.Stderr, "Nothing to add: pass text as arguments or pipe it on stdin")
+		os.Exit(1)
+	}
+
+	item := types.ClipboardItem{
+		Content: content,
+		Type:    addFlags.typ,
+		Tags:    addFlags.tags,
+		Pinned:  addFlags.pin,
+	}
+	if item.Type != "" && !types.KnownType(item.Type) {
+		fmt.Fprintf(os.Stderr, "Unknown type %q, expected one of %s\n", item.Type, strings.Join(types.AllTypes(), ", "))
+		os.Exit(1)
+	}
+	if addFlags.ttl < 0 {
+		fmt.Fprintln(os.Stderr, "--ttl must not be negative")
+		os.Exit(1)
+	}
+	if types.IsBinary(content) {
+		if cfg.Storage.Binary != config.BinaryStore {
+			fmt.Fprintf(os.Stderr, "Refusing to add %d bytes of binary data; set storage.binary = %q to keep it\n",
+				len(content), config.BinaryStore)
+			os.Exit(1)
+		}
+		item.Type = types.TypeBinary
+	}
+
+	if item.Type == "" {
+		item.Type = types.DetectType(content)
+	}
+	if rules, err := cfg.TypeRules(); err == nil {
+		var keep bool
+		if item, keep = rules.Apply(item); !keep {
+			fmt.Fprintf(os.Stderr, "Not adding %s item: its type rule skips it\n", item.Type)
+			return
+		}
+	}
+	// --ttl wins over an expire action
+	if addFlags.ttl > 0 {
+		item.ExpiresAt = time.Now().Add(addFlags.ttl)
+	}
+
+	store := openStore(cmd)
+	defer store.Close()
+
+	if _, err := store.AddItem(item); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to add item: %v\n", err)
+		os.Exit(1)
+	}
+
+	if addFlags.copy {
+		if err := clipboard.SetClipboard(content); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to copy to clipboard: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// readContent joins the arguments, or reads stdin when there are none
+func readContent(args []string) (string, error) {
+	if len(args) > 0 {
+		return strings.Join(args, " "), nil
+	}
+
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return "", nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	return string(data), err
+}
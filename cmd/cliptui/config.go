@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dvd/cliptui/internal/config"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+var configForce bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the configuration file",
+	Long:  "Creates, shows, and validates the clipTUI configuration file.",
+	// Subcommands must work with a broken config file, so loading errors are
+	// left to each of them
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, _ = loadConfig(cmd)
+		return nil
+	},
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a commented default config file",
+	Long:  "Writes a config file documenting every setting with its default value.",
+	Run: func(cmd *cobra.Command, args []string) {
+		configInit()
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	Long:  "Prints every setting after merging defaults, the config file, environment variables, and flags, along with where each value came from.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configShow(cmd)
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for errors",
+	Long:  "Reports syntax errors, unknown keys, type errors, and invalid values such as regular expressions or keybindings, with line numbers.",
+	Run: func(cmd *cobra.Command, args []string) {
+		configValidate()
+	},
+}
+
+func init() {
+	configInitCmd.Flags().BoolVarP(&configForce, "force", "f", false, "Overwrite an existing config file")
+
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+}
+
+// configPath returns the config file selected with --config or the default location
+func configPath() string {
+	if flagValues.configPath != "" {
+		return flagValues.configPath
+	}
+	return config.DefaultPath()
+}
+
+// checkConfig validates the loaded config file and effective settings
+func checkConfig() error {
+	var problems []config.Problem
+	if cfg.File != "" {
+		fileProblems, err := config.ValidateFile(cfg.File)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, fileProblems...)
+	}
+	if len(problems) == 0 {
+		problems = cfg.Validate()
+	}
+
+	if len(problems) > 0 {
+		return &config.ValidationError{File: cfg.File, Problems: problems}
+	}
+	return nil
+}
+
+func configInit() {
+	path := configPath()
+	if err := config.WriteDefault(path, configForce); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write config: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Wrote default config to %s\n", path)
+}
+
+func configShow(cmd *cobra.Command) error {
+	loaded, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	if loaded.File != "" {
+		fmt.Printf("# Config file: %s\n", loaded.File)
+	} else {
+		fmt.Printf("# Config file: none (looked for %s)\n", configPath())
+	}
+
+	settings := loaded.Settings()
+
+	width := 0
+	for _, s := range settings {
+		if n := types.StringWidth(s.Key + s.Value); n > width {
+			width = n
+		}
+	}
+	for _, s := range settings {
+		fmt.Printf("%s = %s  # %s\n", s.Key, types.Pad(s.Value, width-types.StringWidth(s.Key)), s.Source)
+	}
+	return nil
+}
+
+func configValidate() {
+	path := configPath()
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(os.Stderr, "No config file at %s\n", path)
+		os.Exit(1)
+	}
+
+	problems, err := config.ValidateFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(problems) == 0 {
+		fmt.Printf("%s is valid.\n", path)
+		return
+	}
+
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", path, problemLocation(p))
+	}
+	os.Exit(1)
+}
+
+// problemLocation formats a problem as "line: key: message" for compiler-style output
+func problemLocation(p config.Problem) string {
+	location := ""
+	if p.Line > 0 {
+		location = fmt.Sprintf("%d:", p.Line)
+	}
+	if p.Key != "" {
+		return fmt.Sprintf("%s %s: %s", location, p.Key, p.Message)
+	}
+	return fmt.Sprintf("%s %s", location, p.Message)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dvd/cliptui/internal/clipboard"
+	"github.com/dvd/cliptui/internal/config"
+	"github.com/dvd/cliptui/internal/daemon"
+	"github.com/dvd/cliptui/internal/hooks"
+	"github.com/dvd/cliptui/internal/ipc"
+	"github.com/dvd/cliptui/internal/storage"
+	"github.com/dvd/cliptui/pkg/client"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Start the clipboard monitoring daemon",
+	Long:  "Runs the clipboard monitor in the foreground, watching for clipboard changes.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runDaemon(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "Daemon error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the running daemon",
+	Long:  "Reports uptime, capture counts, clipboard backend, and the last error of the running daemon.",
+	Run: func(cmd *cobra.Command, args []string) {
+		daemonStatus()
+	},
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running daemon",
+	Long:  "Asks the running daemon to shut down gracefully.",
+	Run: func(cmd *cobra.Command, args []string) {
+		daemonStop()
+	},
+}
+
+func init() {
+	daemonCmd.AddCommand(daemonStatusCmd)
+	daemonCmd.AddCommand(daemonStopCmd)
+}
+
+// dialDaemon connects to the running daemon and exits if there is none
+func dialDaemon() *client.Client {
+	c, err := client.Dial(cfg.Storage.SocketPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Daemon is not running (no socket at %s)\n", cfg.Storage.SocketPath)
+		os.Exit(1)
+	}
+	return c
+}
+
+// applyMonitorConfig pushes the reloadable settings to the monitor
+func applyMonitorConfig(monitor *clipboard.Monitor, c *config.Config) error {
+	patterns, err := c.IgnorePatterns()
+	if err != nil {
+		return err
+	}
+	rules, err := c.TypeRules()
+	if err != nil {
+		return err
+	}
+
+	monitor.SetPollInterval(c.Monitor.PollInterval)
+	monitor.SetIgnorePatterns(patterns)
+	monitor.SetRetention(c.Retention.MaxItems, c.Retention.MaxAge)
+	monitor.SetHooks(hooks.NewRunner(c.Hooks.Dir, c.Hooks.Timeout))
+	monitor.SetTypeRules(rules)
+	rules.Install()
+	return nil
+}
+
+// reloadConfig re-reads the configuration on SIGHUP. Storage and socket
+// locations are fixed for the lifetime of the daemon and need a restart.
+func reloadConfig(cmd *cobra.Command, monitor *clipboard.Monitor) {
+	reloaded, err := loadConfig(cmd)
+	if err == nil {
+		if problems := reloaded.Validate(); len(problems) > 0 {
+			err = &config.ValidationError{File: reloaded.File, Problems: problems}
+		}
+	}
+	if err == nil {
+		err = applyMonitorConfig(monitor, reloaded)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to reload config, keeping current settings: %v\n", err)
+		return
+	}
+
+	if reloaded.Storage != cfg.Storage {
+		fmt.Println("Storage settings changed; restart the daemon to apply them.")
+		reloaded.Storage = cfg.Storage
+	}
+	cfg = reloaded
+
+	fmt.Printf("Config reloaded (poll interval %s)\n", cfg.Monitor.PollInterval)
+}
+
+// runDaemon monitors the clipboard and serves clients until it is stopped.
+// It fails when the config cannot be applied, the database cannot be opened
+// or the socket cannot be served, releasing the lock on the way out.
+func runDaemon(cmd *cobra.Command) error {
+	if err := checkConfig(); err != nil {
+		return fmt.Errorf("refusing to start daemon: %w", err)
+	}
+
+	lock, err := daemon.AcquireLock(cfg.Storage.PIDPath)
+	if err != nil {
+		return fmt.Errorf("cannot start daemon: %w", err)
+	}
+	defer lock.Release()
+
+	store, err := storage.New(cfg.Storage.DBPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer store.Close()
+
+	monitor := clipboard.NewMonitor(store, cfg.Monitor.PollInterval)
+	if err := applyMonitorConfig(monitor, cfg); err != nil {
+		return fmt.Errorf("apply config: %w", err)
+	}
+	server := ipc.NewServer(store, monitor)
+	store.SetObserver(server.Publish)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Handle graceful shutdown, and reload the config on SIGHUP
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	go func() {
+		for sig := range sigChan {
+			if sig == syscall.SIGHUP {
+				reloadConfig(cmd, monitor)
+				continue
+			}
+			fmt.Println("\nShutting down daemon...")
+			cancel()
+			return
+		}
+	}()
+
+	server.OnShutdown(func() {
+		fmt.Println("Stop requested, shutting down daemon...")
+		cancel()
+	})
+
+	// Without the socket no client can reach the daemon, so it stops too
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := server.Serve(ctx, cfg.Storage.SocketPath); err != nil {
+			serveErr <- err
+			cancel()
+		}
+	}()
+
+	fmt.Println("Starting clipboard monitor daemon...")
+	fmt.Printf("Database: %s\n", cfg.Storage.DBPath)
+	fmt.Printf("Socket: %s\n", cfg.Storage.SocketPath)
+	if cfg.File != "" {
+		fmt.Printf("Config: %s\n", cfg.File)
+	}
+	fmt.Printf("Poll interval: %s\n", cfg.Monitor.PollInterval)
+	fmt.Println("Press Ctrl+C to stop.")
+
+	err = monitor.Start(ctx)
+	select {
+	case serr := <-serveErr:
+		return fmt.Errorf("IPC server: %w", serr)
+	default:
+	}
+	if err != nil && err != context.Canceled {
+		return err
+	}
+	return nil
+}
+
+func daemonStatus() {
+	c := dialDaemon()
+	defer c.Close()
+
+	status, err := c.Status()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get daemon status: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("PID:          %d\n", status.PID)
+	fmt.Printf("State:        %s\n", captureState(status))
+
+	fmt.Printf("Uptime:       %s\n", time.Since(status.StartedAt).Round(time.Second))
+	fmt.Printf("Captures:     %d\n", status.Captures)
+	if !status.LastCapture.IsZero() {
+		fmt.Printf("Last capture: %s\n", status.LastCapture.Format(time.DateTime))
+	}
+	fmt.Printf("Backend:      %s\n", status.Backend)
+	fmt.Printf("Database:     %s\n", status.Database)
+	fmt.Printf("Socket:       %s\n", status.Socket)
+	if status.LastError != "" {
+		fmt.Printf("Last error:   %s\n", status.LastError)
+	}
+}
+
+// captureState describes whether the daemon is capturing or paused
+func captureState(status *client.Status) string {
+	if !status.Paused {
+		return "capturing"
+	}
+	if status.PausedUntil.IsZero() {
+		return "paused until resumed"
+	}
+	return fmt.Sprintf("paused until %s", status.PausedUntil.Format(time.TimeOnly))
+}
+
+func daemonStop() {
+	c := dialDaemon()
+	defer c.Close()
+
+	if err := c.Shutdown(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to stop daemon: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Daemon is shutting down.")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dvd/cliptui/internal/language"
+	"github.com/dvd/cliptui/internal/storage"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+var reclassifyFlags struct {
+	types  []string
+	dryRun bool
+}
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Maintain the history database",
+	Long:  "Commands that update the items already stored in the history database.",
+}
+
+var dbReclassifyCmd = &cobra.Command{
+	Use:   "reclassify",
+	Short: "Detect the type of stored items again",
+	Long: `Runs content type detection on every stored item and updates the items
+whose type changed, for example after upgrading to a version that knows more
+types. Code stored without a language gets one. Binary items are left alone.
+Types set by hooks or 'cliptui add --type' are replaced too, unless --type
+limits the items that are looked at.`,
+	Example: `  cliptui db reclassify --dry-run
+  cliptui db reclassify --type text --type code`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		reclassifyItems(cmd)
+	},
+}
+
+func init() {
+	dbReclassifyCmd.Flags().StringSliceVarP(&reclassifyFlags.types, "type", "t", nil, "Only look at items currently of these types (repeatable)")
+	dbReclassifyCmd.Flags().BoolVarP(&reclassifyFlags.dryRun, "dry-run", "n", false, "Print the changes without saving them")
+
+	dbCmd.AddCommand(dbReclassifyCmd)
+}
+
+func reclassifyItems(cmd *cobra.Command) {
+	for _, t := range reclassifyFlags.types {
+		if !types.KnownType(t) {
+			fmt.Fprintf(os.Stderr, "Unknown type %q, expected one of %s\n", t, strings.Join(types.AllTypes(), ", "))
+			os.Exit(1)
+		}
+	}
+
+	store := openStore(cmd)
+	defer store.Close()
+
+	items, err := store.GetAll()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read history: %v\n", err)
+		os.Exit(1)
+	}
+
+	changed := 0
+	for _, item := range items {
+		if item.Type == types.TypeBinary {
+			continue
+		}
+		if len(reclassifyFlags.types) > 0 && !slices.Contains(reclassifyFlags.types, item.Type) {
+			continue
+		}
+
+		detected := types.DetectType(item.Content)
+		if detected == item.Type {
+			if detected == types.TypeCode && item.Language == "" {
+				changed += detectLanguage(store, item)
+			}
+			continue
+		}
+		fmt.Printf("%d\t%s -> %s\t%s\n", item.ID, item.Type, detected, types.SingleLine(item.Preview, 50))
+		changed++
+		if reclassifyFlags.dryRun {
+			continue
+		}
+		if err := store.SetType(item.ID, detected); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to update item %d: %v\n", item.ID, err)
+			os.Exit(1)
+		}
+	}
+
+	if reclassifyFlags.dryRun {
+		fmt.Fprintf(os.Stderr, "%d of %d items would change\n", changed, len(items))
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Reclassified %d of %d items\n", changed, len(items))
+}
+
+// detectLanguage stores the language of a code item stored before languages
+// were detected, returning 1 if it found one
+func detectLanguage(store storage.Store, item types.ClipboardItem) int {
+	lang := language.Detect(item.Content)
+	if lang == "" {
+		return 0
+	}
+	fmt.Printf("%d\tcode -> code (%s)\t%s\n", item.ID, lang, types.SingleLine(item.Preview, 50))
+	if reclassifyFlags.dryRun {
+		return 1
+	}
+	if err := store.SetLanguage(item.ID, lang); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to update item %d: %v\n", item.ID, err)
+		os.Exit(1)
+	}
+	return 1
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dvd/cliptui/internal/clipboard"
+	"github.com/dvd/cliptui/internal/storage"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+// itemRefHelp explains the item references accepted by get and copy
+const itemRefHelp = `An item is given by its ID, as shown by 'cliptui list', or by its position
+in the history: @0 is the most recent item, @1 the one before it, and so on.`
+
+var getCmd = &cobra.Command{
+	Use:   "get <id|@index>",
+	Short: "Print a history item",
+	Long: `Prints the content of a history item to stdout exactly as it was copied,
+without adding a trailing newline.
+
+` + itemRefHelp,
+	Example: `  cliptui get 42
+  cliptui get @0 > latest.txt`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		getItem(cmd, args[0])
+	},
+}
+
+var copyCmd = &cobra.Command{
+	Use:   "copy <id|@index>",
+	Short: "Copy a history item back to the clipboard",
+	Long: `Puts the content of a history item back on the system clipboard.
+
+` + itemRefHelp,
+	Example: `  cliptui copy @1   # restore the second most recent clip`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		copyItem(cmd, args[0])
+	},
+}
+
+func getItem(cmd *cobra.Command, ref string) {
+	item := lookupItem(cmd, ref)
+	if _, err := os.Stdout.WriteString(item.Content); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write item: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func copyItem(cmd *cobra.Command, ref string) {
+	item := lookupItem(cmd, ref)
+	if err := clipboard.SetClipboard(item.Content); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to copy to clipboard: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// lookupItem resolves an item reference, exiting if it does not exist
+func lookupItem(cmd *cobra.Command, ref string) *types.ClipboardItem {
+	store := openStore(cmd)
+	defer store.Close()
+
+	item, err := resolveItem(store, ref)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get item: %v\n", err)
+		os.Exit(1)
+	}
+	if item == nil {
+		fmt.Fprintf(os.Stderr, "No item %s in history\n", ref)
+		os.Exit(1)
+	}
+	return item
+}
+
+// resolveItem finds the item named by an ID or an @index counted from the
+// most recent item. It returns nil if there is no such item.
+func resolveItem(store storage.Store, ref string) (*types.ClipboardItem, error) {
+	if index, ok := strings.CutPrefix(ref, "@"); ok {
+		n, err := strconv.Atoi(index)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid index %q, expected @0, @1, ...", ref)
+		}
+		items, err := store.List(types.Query{Limit: n + 1})
+		if err != nil {
+			return nil, err
+		}
+		if n >= len(items) {
+			return nil, nil
+		}
+		return &items[n], nil
+	}
+
+	id, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid item %q, expected an ID or @index", ref)
+	}
+	return store.Get(id)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dvd/cliptui/internal/export"
+	"github.com/dvd/cliptui/internal/search"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+var listFlags struct {
+	limit  int
+	typ    string
+	since  string
+	until  string
+	pinned bool
+	tag    string
+	query  string
+	format string
+	null   bool
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print clipboard history",
+	Long: `Prints clipboard history, newest first, without opening the TUI.
+
+--since and --until take a duration back from now (30m, 2h, 7d) or a time
+such as 2024-05-01 or 2024-05-01T14:00:00Z.
+
+--query takes the same syntax as the TUI search box, with words and quoted
+phrases matched as substrings: type:url, tag:work, pinned:true, after:2d,
+before:2024-05-01, len>200, "exact phrase", and -term to exclude.
+
+The json and ndjson formats contain every field of each item. The tsv format
+prints id, timestamp, type, pinned and content separated by tabs, with tabs,
+newlines and backslashes in the content escaped. With --null records end in a
+NUL byte and tsv content is written unescaped.`,
+	Example: `  cliptui list --limit 5
+  cliptui list --type url --since 1d --format json
+  cliptui list --query 'tag:work -draft len>200'
+  cliptui list --format tsv --null | fzf --read0 --delimiter '\t' --with-nth 5..`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listHistory(cmd)
+	},
+}
+
+func init() {
+	flags := listCmd.Flags()
+	flags.IntVarP(&listFlags.limit, "limit", "n", 0, "Maximum number of items (0 for all)")
+	flags.StringVarP(&listFlags.typ, "type", "t", "", "Only items of this type ("+strings.Join(types.Types, ", ")+")")
+	flags.StringVar(&listFlags.since, "since", "", "Only items copied at or after this time")
+	flags.StringVar(&listFlags.until, "until", "", "Only items copied before this time")
+	flags.BoolVar(&listFlags.pinned, "pinned", false, "Only pinned items")
+	flags.StringVar(&listFlags.tag, "tag", "", "Only items with this tag")
+	flags.StringVarP(&listFlags.query, "query", "q", "", "Only items matching a search query, e.g. 'type:url -draft'")
+	flags.StringVarP(&listFlags.format, "format", "f", export.FormatTable, "Output format: "+strings.Join(export.Formats, ", "))
+	flags.BoolVarP(&listFlags.null, "null", "0", false, "End records with NUL instead of newline (ndjson, tsv)")
+}
+
+func listHistory(cmd *cobra.Command) {
+	q := types.Query{
+		Limit:  listFlags.limit,
+		Type:   listFlags.typ,
+		Pinned: listFlags.pinned,
+		Tag:    listFlags.tag,
+		Search: listFlags.query,
+	}
+
+	var err error
+	if q.Since, err = search.ParseTime(listFlags.since); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --since: %v\n", err)
+		os.Exit(1)
+	}
+	if q.Until, err = search.ParseTime(listFlags.until); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --until: %v\n", err)
+		os.Exit(1)
+	}
+
+	if _, err := search.Parse(q.Search); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --query: %v\n", err)
+		os.Exit(1)
+	}
+
+	store := openStore(cmd)
+	defer store.Close()
+
+	items, err := store.List(q)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list history: %v\n", err)
+		os.Exit(1)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	opts := export.Options{Format: listFlags.format, Null: listFlags.null}
+	if err := export.Write(out, items, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write history: %v\n", err)
+		os.Exit(1)
+	}
+	// A reader that quits early, like head, closes the pipe
+	if err := out.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write history: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -1,40 +1,59 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
+	"path/filepath"
 	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/dvd/cliptui/internal/clipboard"
 	"github.com/dvd/cliptui/internal/config"
 	"github.com/dvd/cliptui/internal/storage"
 	"github.com/dvd/cliptui/internal/tui"
+	"github.com/dvd/cliptui/pkg/client"
 )
 
 var cfg *config.Config
 
+// flagValues holds command-line overrides, applied on top of the loaded config
+var flagValues struct {
+	configPath   string
+	dbPath       string
+	socketPath   string
+	maxItems     int
+	pollInterval time.Duration
+	debugLog     string
+	stayOpen     bool
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "cliptui",
 	Short: "A beautiful terminal-based clipboard history manager",
 	Long: `clipTUI is a modern, fast, and elegant clipboard history manager for Linux.
 It watches your system clipboard in the background, stores every item locally,
 and lets you browse, search, preview, and restore previous clipboard entries.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		showTUI()
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Flags parsed fine at this point, so usage would not help with config errors
+		cmd.SilenceUsage = true
+
+		var err error
+		cfg, err = loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Custom types are detected and accepted as filters by every command
+		rules, err := cfg.TypeRules()
+		if err != nil {
+			return err
+		}
+		rules.Install()
+		return nil
 	},
-}
-
-var daemonCmd = &cobra.Command{
-	Use:   "daemon",
-	Short: "Start the clipboard monitoring daemon",
-	Long:  "Runs the clipboard monitor in the foreground, watching for clipboard changes.",
 	Run: func(cmd *cobra.Command, args []string) {
-		runDaemon()
+		showTUI(cmd)
 	},
 }
 
@@ -43,7 +62,7 @@ var showCmd = &cobra.Command{
 	Short: "Show the clipboard history TUI",
 	Long:  "Opens the terminal UI to browse, search, and restore clipboard history.",
 	Run: func(cmd *cobra.Command, args []string) {
-		showTUI()
+		showTUI(cmd)
 	},
 }
 
@@ -52,19 +71,73 @@ var clearCmd = &cobra.Command{
 	Short: "Clear all clipboard history",
 	Long:  "Deletes all stored clipboard items from the database.",
 	Run: func(cmd *cobra.Command, args []string) {
-		clearHistory()
+		clearHistory(cmd)
 	},
 }
 
 func init() {
-	cfg = config.Default()
+	defaults := config.Default()
 
 	rootCmd.AddCommand(daemonCmd)
 	rootCmd.AddCommand(showCmd)
 	rootCmd.AddCommand(clearCmd)
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(getCmd)
+	rootCmd.AddCommand(copyCmd)
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(pickCmd)
+	rootCmd.AddCommand(decodeCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(dbCmd)
+
+	rootCmd.PersistentFlags().StringVar(&flagValues.configPath, "config", "", "Config file (default "+config.DefaultPath()+")")
+	rootCmd.PersistentFlags().StringVar(&flagValues.dbPath, "db", defaults.Storage.DBPath, "Database path")
+	rootCmd.PersistentFlags().IntVar(&flagValues.maxItems, "max-items", defaults.Retention.MaxItems, "Maximum items to store")
+	rootCmd.PersistentFlags().StringVar(&flagValues.socketPath, "socket", defaults.Storage.SocketPath, "Daemon socket path")
+	rootCmd.PersistentFlags().DurationVar(&flagValues.pollInterval, "poll-interval", defaults.Monitor.PollInterval, "Clipboard poll interval")
+
+	// The TUI is started by the root and show commands
+	for _, cmd := range []*cobra.Command{rootCmd, showCmd} {
+		cmd.Flags().StringVar(&flagValues.debugLog, "debug", "", "Record TUI errors in a log file; another path must follow an =, as in --debug=/tmp/cliptui.log")
+		cmd.Flags().Lookup("debug").NoOptDefVal = config.DebugLogPath()
+		cmd.Flags().BoolVar(&flagValues.stayOpen, "stay-open", defaults.UI.StayOpen, "Keep the TUI open after copying an item")
+	}
+}
 
-	rootCmd.PersistentFlags().StringVar(&cfg.DBPath, "db", cfg.DBPath, "Database path")
-	rootCmd.PersistentFlags().IntVar(&cfg.MaxItems, "max-items", cfg.MaxItems, "Maximum items to store")
+// loadConfig loads the config file and environment, then applies the flags
+// that were set explicitly, giving flags > env > file > defaults
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	loaded, err := config.Load(flagValues.configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("db") {
+		loaded.Storage.DBPath = flagValues.dbPath
+		loaded.SetSource("storage.db_path", config.SourceFlag)
+	}
+	if flags.Changed("socket") {
+		loaded.Storage.SocketPath = flagValues.socketPath
+		loaded.SetSource("storage.socket_path", config.SourceFlag)
+	}
+	if flags.Changed("max-items") {
+		loaded.Retention.MaxItems = flagValues.maxItems
+		loaded.SetSource("retention.max_items", config.SourceFlag)
+	}
+	if flags.Changed("poll-interval") {
+		loaded.Monitor.PollInterval = flagValues.pollInterval
+		loaded.SetSource("monitor.poll_interval", config.SourceFlag)
+	}
+	if flags.Changed("stay-open") {
+		loaded.UI.StayOpen = flagValues.stayOpen
+		loaded.SetSource("ui.stay_open", config.SourceFlag)
+	}
+
+	return loaded, nil
 }
 
 func main() {
@@ -76,7 +149,7 @@ func main() {
 
 // openStorage opens the storage database and handles errors
 func openStorage() *storage.Storage {
-	store, err := storage.New(cfg.DBPath)
+	store, err := storage.New(cfg.Storage.DBPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to open database: %v\n", err)
 		os.Exit(1)
@@ -84,54 +157,56 @@ func openStorage() *storage.Storage {
 	return store
 }
 
-func runDaemon() {
-	store := openStorage()
-	defer store.Close()
-
-	monitor := clipboard.NewMonitor(store, time.Duration(cfg.PollInterval)*time.Millisecond)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Handle graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
-	go func() {
-		<-sigChan
-		fmt.Println("\nShutting down daemon...")
-		cancel()
-	}()
-
-	fmt.Println("Starting clipboard monitor daemon...")
-	fmt.Printf("Database: %s\n", cfg.DBPath)
-	fmt.Printf("Poll interval: %dms\n", cfg.PollInterval)
-	fmt.Println("Press Ctrl+C to stop.")
-
-	if err := monitor.Start(ctx); err != nil && err != context.Canceled {
-		fmt.Fprintf(os.Stderr, "Daemon error: %v\n", err)
-		os.Exit(1)
+// openStore connects to the running daemon, falling back to opening the
+// database directly when no daemon is listening. An explicit --db always
+// opens the database directly, since the daemon may be using another one.
+func openStore(cmd *cobra.Command) storage.Store {
+	if !cmd.Flags().Changed("db") {
+		if c, err := client.Dial(cfg.Storage.SocketPath); err == nil {
+			return c
+		}
 	}
+	return openStorage()
 }
 
-func showTUI() {
-	store := openStorage()
+func showTUI(cmd *cobra.Command) {
+	store := openStore(cmd)
 	defer store.Close()
 
-	app, err := tui.New(store)
+	app, err := tui.New(store, cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create TUI: %v\n", err)
 		os.Exit(1)
 	}
 
+	if flagValues.debugLog != "" {
+		logFile, err := openDebugLog(flagValues.debugLog)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open debug log: %v\n", err)
+			os.Exit(1)
+		}
+		defer logFile.Close()
+		app.SetDebugLog(logFile)
+	}
+
 	if err := app.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "TUI error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func clearHistory() {
-	store := openStorage()
+// openDebugLog opens a log file for appending, creating it and its directory
+// as needed. The log may quote clipboard content, so only the user can read it.
+func openDebugLog(path string) (*os.File, error) {
+	path = config.ExpandHome(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+}
+
+func clearHistory(cmd *cobra.Command) {
+	store := openStore(cmd)
 	defer store.Close()
 
 	if err := store.Clear(); err != nil {
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var pauseFor time.Duration
+
+var pauseCmd = &cobra.Command{
+	Use:     "pause",
+	Aliases: []string{"incognito"},
+	Short:   "Stop recording clipboard changes",
+	Long: `Tells the running daemon to stop recording clipboard changes.
+Without --for, capture stays paused until 'cliptui resume' is run.
+Anything copied while paused is never written to the history.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		pauseCapture()
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume recording clipboard changes",
+	Long:  "Tells the running daemon to start recording clipboard changes again.",
+	Run: func(cmd *cobra.Command, args []string) {
+		resumeCapture()
+	},
+}
+
+func init() {
+	pauseCmd.Flags().DurationVar(&pauseFor, "for", 0, "Resume automatically after this duration (e.g. 5m)")
+}
+
+func pauseCapture() {
+	c := dialDaemon()
+	defer c.Close()
+
+	if err := c.Pause(pauseFor); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to pause capture: %v\n", err)
+		os.Exit(1)
+	}
+
+	if pauseFor > 0 {
+		fmt.Printf("Clipboard capture paused for %s.\n", pauseFor)
+	} else {
+		fmt.Println("Clipboard capture paused until 'cliptui resume'.")
+	}
+}
+
+func resumeCapture() {
+	c := dialDaemon()
+	defer c.Close()
+
+	if err := c.Resume(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resume capture: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Clipboard capture resumed.")
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dvd/cliptui/internal/clipboard"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+// pickFormatDmenu prints "<id>\t<preview>" lines
+const pickFormatDmenu = "dmenu"
+
+var pickFlags struct {
+	format string
+	limit  int
+	width  int
+}
+
+var decodeCopy bool
+
+var pickCmd = &cobra.Command{
+	Use:   "pick",
+	Short: "Print history for dmenu, rofi, wofi or fzf",
+	Long: `Prints one line per history item, newest first, made of the item ID, a tab
+and a single-line preview. Pipe the line chosen by the picker into
+'cliptui decode' to get the full item back.`,
+	Example: `  cliptui pick | dmenu | cliptui decode --copy
+  cliptui pick | rofi -dmenu -display-columns 2 | cliptui decode --copy
+  cliptui pick | fzf --delimiter '\t' --with-nth 2 | cliptui decode`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		pickItems(cmd)
+	},
+}
+
+var decodeCmd = &cobra.Command{
+	Use:   "decode [line]",
+	Short: "Print or copy the item selected from 'cliptui pick'",
+	Long: `Reads a line printed by 'cliptui pick' from the argument or stdin and prints
+the full content of its item exactly as it was copied, or puts it on the
+clipboard with --copy.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		decodeItem(cmd, args)
+	},
+}
+
+func init() {
+	pickCmd.Flags().StringVarP(&pickFlags.format, "format", "f", pickFormatDmenu, "Output format (dmenu)")
+	pickCmd.Flags().IntVarP(&pickFlags.limit, "limit", "n", 0, "Maximum number of items (0 for all)")
+	pickCmd.Flags().IntVarP(&pickFlags.width, "width", "w", 100, "Maximum preview length")
+
+	decodeCmd.Flags().BoolVar(&decodeCopy, "copy", false, "Copy the item to the clipboard instead of printing it")
+}
+
+func pickItems(cmd *cobra.Command) {
+	if pickFlags.format != pickFormatDmenu {
+		fmt.Fprintf(os.Stderr, "Unknown format %q, expected %s\n", pickFlags.format, pickFormatDmenu)
+		os.Exit(1)
+	}
+	if pickFlags.width < 4 {
+		fmt.Fprintln(os.Stderr, "--width must be at least 4")
+		os.Exit(1)
+	}
+
+	store := openStore(cmd)
+	defer store.Close()
+
+	items, err := store.List(types.Query{Limit: pickFlags.limit})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list history: %v\n", err)
+		os.Exit(1)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	for _, item := range items {
+		fmt.Fprintf(out, "%d\t%s\n", item.ID, types.SingleLine(item.Preview, pickFlags.width))
+	}
+	// A picker that quits early closes the pipe
+	if err := out.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write history: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func decodeItem(cmd *cobra.Command, args []string) {
+	var line string
+	if len(args) > 0 {
+		line = args[0]
+	} else {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read selection: %v\n", err)
+			os.Exit(1)
+		}
+		line = string(data)
+	}
+
+	id, err := parsePickLine(line)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to decode selection: %v\n", err)
+		os.Exit(1)
+	}
+
+	item := lookupItem(cmd, strconv.FormatInt(id, 10))
+	if decodeCopy {
+		if err := clipboard.SetClipboard(item.Content); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to copy to clipboard: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if _, err := os.Stdout.WriteString(item.Content); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write item: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parsePickLine extracts the item ID from a line printed by pick. Only the
+// first line counts, and anything after the ID is ignored, so pickers that
+// strip or alter the preview still work.
+func parsePickLine(line string) (int64, error) {
+	line, _, _ = strings.Cut(line, "\n")
+	field, _, _ := strings.Cut(line, "\t")
+	field = strings.TrimSpace(field)
+	if field == "" {
+		return 0, fmt.Errorf("nothing selected")
+	}
+
+	id, err := strconv.ParseInt(field, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q does not start with an item ID", types.SingleLine(line, 40))
+	}
+	return id, nil
+}
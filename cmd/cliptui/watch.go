@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dvd/cliptui/internal/storage"
+	"github.com/dvd/cliptui/pkg/client"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+var watchFlags struct {
+	types []string
+	exec  string
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Stream history changes as JSON",
+	Long: `Prints one JSON object per line for every item that is added, updated or
+deleted, and for clearing the history, until interrupted:
+
+  {"kind":"added","id":42,"item":{"id":42,"content":"...","type":"url",...}}
+
+Events come from the running daemon. Without a daemon, or with --db, the
+database is polled instead.
+
+--exec runs a shell command for every added or updated item, with the
+content on stdin and CLIPTUI_EVENT, CLIPTUI_ID and CLIPTUI_TYPE set.`,
+	Example: `  cliptui watch --type url
+  cliptui watch --exec 'notify-send "Copied" "$(head -c 100)"'`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		watchHistory(cmd)
+	},
+}
+
+func init() {
+	watchCmd.Flags().StringSliceVarP(&watchFlags.types, "type", "t", nil, "Only events for items of these types (repeatable)")
+	watchCmd.Flags().StringVar(&watchFlags.exec, "exec", "", "Run a shell command for each added or updated item")
+}
+
+func watchHistory(cmd *cobra.Command) {
+	for _, t := range watchFlags.types {
+		if !types.KnownType(t) {
+			fmt.Fprintf(os.Stderr, "Unknown type %q, expected one of %s\n", t, strings.Join(types.AllTypes(), ", "))
+			os.Exit(1)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store := openStore(cmd)
+	defer store.Close()
+
+	// Remember item types so that deletions can be filtered too
+	known := make(map[int64]string)
+	items, err := store.GetAll()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read history: %v\n", err)
+		os.Exit(1)
+	}
+	for _, item := range items {
+		known[item.ID] = item.Type
+	}
+
+	var events <-chan types.Event
+	if c, ok := store.(*client.Client); ok {
+		events, err = c.Subscribe(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to subscribe to daemon: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		events = pollEvents(ctx, store, items, cfg.Monitor.PollInterval)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for event := range events {
+		itemType := known[event.ID]
+		if event.Item != nil {
+			itemType = event.Item.Type
+			known[event.ID] = itemType
+		}
+		if event.Kind == types.EventDeleted {
+			delete(known, event.ID)
+		}
+		if event.Kind == types.EventCleared {
+			clear(known)
+		}
+
+		if len(watchFlags.types) > 0 && event.Kind != types.EventCleared &&
+			!slices.Contains(watchFlags.types, itemType) {
+			continue
+		}
+
+		if err := enc.Encode(event); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write event: %v\n", err)
+			os.Exit(1)
+		}
+		if watchFlags.exec != "" && event.Item != nil {
+			runWatchCommand(ctx, event)
+		}
+	}
+
+	if ctx.Err() == nil {
+		fmt.Fprintln(os.Stderr, "Lost connection to the daemon")
+		os.Exit(1)
+	}
+}
+
+// runWatchCommand runs --exec for an event, reporting failures without stopping
+func runWatchCommand(ctx context.Context, event types.Event) {
+	c := exec.CommandContext(ctx, "sh", "-c", watchFlags.exec)
+	c.Stdin = strings.NewReader(event.Item.Content)
+	c.Stdout = os.Stderr // keep stdout for events
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(),
+		"CLIPTUI_EVENT="+event.Kind,
+		"CLIPTUI_ID="+strconv.FormatInt(event.ID, 10),
+		"CLIPTUI_TYPE="+event.Item.Type,
+	)
+	if err := c.Run(); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "--exec failed for item %d: %v\n", event.ID, err)
+	}
+}
+
+// pollEvents reports changes to the database by comparing snapshots of the
+// history every interval, for when no daemon is running
+func pollEvents(ctx context.Context, store storage.Store, items []types.ClipboardItem, interval time.Duration) <-chan types.Event {
+	events := make(chan types.Event)
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		previous := make(map[int64]types.ClipboardItem, len(items))
+		for _, item := range items {
+			previous[item.ID] = item
+		}
+
+		send := func(event types.Event) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			items, err := store.GetAll()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read history: %v\n", err)
+				continue
+			}
+			current := make(map[int64]types.ClipboardItem, len(items))
+			for _, item := range items {
+				current[item.ID] = item
+			}
+
+			if len(current) == 0 && len(previous) > 0 {
+				if !send(types.Event{Kind: types.EventCleared}) {
+					return
+				}
+				previous = current
+				continue
+			}
+
+			for id := range previous {
+				if _, ok := current[id]; !ok {
+					if !send(types.Event{Kind: types.EventDeleted, ID: id}) {
+						return
+					}
+				}
+			}
+			// Oldest first, matching the order in which the daemon reports additions
+			for i := len(items) - 1; i >= 0; i-- {
+				item := items[i]
+				old, ok := previous[item.ID]
+				kind := ""
+				switch {
+				case !ok:
+					kind = types.EventAdded
+				case old.Pinned != item.Pinned || old.Type != item.Type || old.Language != item.Language ||
+					!slices.Equal(old.Tags, item.Tags):
+					kind = types.EventUpdated
+				}
+				if kind != "" && !send(types.Event{Kind: kind, ID: item.ID, Item: &items[i]}) {
+					return
+				}
+			}
+			previous = current
+		}
+	}()
+
+	return events
+}
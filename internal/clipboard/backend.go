@@ -0,0 +1,31 @@
+package clipboard
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Backend returns the name of the clipboard tool used to read and write the
+// clipboard, following the same order of preference as the clipboard library
+func Backend() string {
+	if os.Getenv("WAYLAND_DISPLAY") != "" && hasCommands("wl-copy", "wl-paste") {
+		return "wl-clipboard"
+	}
+
+	for _, name := range []string{"xclip", "xsel", "termux-clipboard-set"} {
+		if hasCommands(name) {
+			return name
+		}
+	}
+	return "none"
+}
+
+// hasCommands reports whether all the named executables are on $PATH
+func hasCommands(names ...string) bool {
+	for _, name := range names {
+		if _, err := exec.LookPath(name); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -2,17 +2,47 @@ package clipboard
 
 import (
 	"context"
+	"regexp"
+	"sync"
 	"time"
 
 	"github.com/atotto/clipboard"
+	"github.com/dvd/cliptui/internal/hooks"
 	"github.com/dvd/cliptui/internal/storage"
+	"github.com/dvd/cliptui/internal/typerules"
+	"github.com/dvd/cliptui/pkg/types"
 )
 
+// expirySweepInterval is how often items past their expiry time are
+// deleted. Storage leaves them out of every read until then.
+const expirySweepInterval = time.Minute
+
 // Monitor watches the clipboard for changes
 type Monitor struct {
-	storage      *storage.Storage
+	storage     *storage.Storage
+	lastContent string
+
+	mu           sync.RWMutex
 	pollInterval time.Duration
-	lastContent  string
+	ignore       []*regexp.Regexp
+	maxItems     int
+	maxAge       time.Duration
+	hooks        *hooks.Runner
+	typeRules    *typerules.Set
+	paused       bool
+	pausedUntil  time.Time
+	captures     int
+	lastCapture  time.Time
+	lastError    error
+}
+
+// Stats summarizes what the monitor has done since it was created
+type Stats struct {
+	Captures    int
+	LastCapture time.Time
+	LastError   error
+	Paused      bool
+	PausedUntil time.Time // zero when paused until Resume is called
 }
 
 // NewMonitor creates a new clipboard monitor
@@ -24,37 +54,261 @@ func NewMonitor(store *storage.Storage, pollInterval time.Duration) *Monitor {
 	}
 }
 
+// SetPollInterval changes how often the clipboard is checked, taking effect
+// on the next tick
+func (m *Monitor) SetPollInterval(d time.Duration) {
+	m.mu.Lock()
+	m.pollInterval = d
+	m.mu.Unlock()
+}
+
+// SetIgnorePatterns sets the expressions whose matching content is never stored
+func (m *Monitor) SetIgnorePatterns(patterns []*regexp.Regexp) {
+	m.mu.Lock()
+	m.ignore = patterns
+	m.mu.Unlock()
+}
+
+// SetRetention limits the stored history to maxItems items no older than
+// maxAge; zero disables the respective limit
+func (m *Monitor) SetRetention(maxItems int, maxAge time.Duration) {
+	m.mu.Lock()
+	m.maxItems = maxItems
+	m.maxAge = maxAge
+	m.mu.Unlock()
+}
+
+// SetHooks sets the runner whose hooks see every capture before it is
+// stored, nil disables hooks
+func (m *Monitor) SetHooks(r *hooks.Runner) {
+	m.mu.Lock()
+	m.hooks = r
+	m.mu.Unlock()
+}
+
+// SetTypeRules sets the rules whose actions run on captures of the types
+// they define, nil runs none
+func (m *Monitor) SetTypeRules(rules *typerules.Set) {
+	m.mu.Lock()
+	m.typeRules = rules
+	m.mu.Unlock()
+}
+
+// ignored reports whether content matches one of the ignore patterns
+func (m *Monitor) ignored(content string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, re := range m.ignore {
+		if re.MatchString(content) {
+			return true
+		}
+	}
+	return false
+}
+
+// prune enforces the retention limits
+func (m *Monitor) prune() {
+	m.mu.RLock()
+	maxItems, maxAge := m.maxItems, m.maxAge
+	m.mu.RUnlock()
+
+	if maxItems <= 0 && maxAge <= 0 {
+		return
+	}
+	if err := m.storage.Prune(maxItems, maxAge); err != nil {
+		m.recordError(err)
+	}
+}
+
+// Pause suspends capturing for d, or until Resume is called if d is zero
+func (m *Monitor) Pause(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.paused = true
+	m.pausedUntil = time.Time{}
+	if d > 0 {
+		m.pausedUntil = time.Now().Add(d)
+	}
+}
+
+// Resume continues capturing after Pause
+func (m *Monitor) Resume() {
+	m.mu.Lock()
+	m.paused = false
+	m.pausedUntil = time.Time{}
+	m.mu.Unlock()
+}
+
+// Paused reports whether capturing is currently suspended, resuming
+// automatically once a timed pause has expired
+func (m *Monitor) Paused() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.paused && !m.pausedUntil.IsZero() && !time.Now().Before(m.pausedUntil) {
+		m.paused = false
+		m.pausedUntil = time.Time{}
+	}
+	return m.paused
+}
+
+// Stats returns a snapshot of the monitor's counters
+func (m *Monitor) Stats() Stats {
+	paused := m.Paused()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return Stats{
+		Captures:    m.captures,
+		LastCapture: m.lastCapture,
+		LastError:   m.lastError,
+		Paused:      paused,
+		PausedUntil: m.pausedUntil,
+	}
+}
+
+// recordCapture counts a successfully stored item
+func (m *Monitor) recordCapture() {
+	m.mu.Lock()
+	m.captures++
+	m.lastCapture = time.Now()
+	m.mu.Unlock()
+}
+
+// recordError remembers the most recent failure
+func (m *Monitor) recordError(err error) {
+	m.mu.Lock()
+	m.lastError = err
+	m.mu.Unlock()
+}
+
 // Start begins monitoring the clipboard
 func (m *Monitor) Start(ctx context.Context) error {
-	ticker := time.NewTicker(m.pollInterval)
+	m.mu.RLock()
+	interval := m.pollInterval
+	m.mu.RUnlock()
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
+	sweep := time.NewTicker(expirySweepInterval)
+	defer sweep.Stop()
+
+	m.prune()
+	m.deleteExpired()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-sweep.C:
+			m.deleteExpired()
 		case <-ticker.C:
+			m.mu.RLock()
+			if m.pollInterval != interval && m.pollInterval > 0 {
+				interval = m.pollInterval
+				ticker.Reset(interval)
+			}
+			m.mu.RUnlock()
+
 			content, err := clipboard.ReadAll()
+
+			// While paused, remember what was copied without storing it so
+			// that it is not picked up as a new item after resuming
+			if m.Paused() {
+				if err != nil {
+					m.recordError(err)
+				} else {
+					m.lastContent = content
+				}
+				continue
+			}
+
 			if err != nil {
+				m.recordError(err)
 				continue
 			}
 
 			if content != "" && content != m.lastContent {
+				if m.ignored(content) {
+					m.lastContent = content
+					continue
+				}
+
 				latest, err := m.storage.GetLatest()
 				if err == nil && latest != nil && latest.Content == content {
 					m.lastContent = content
 					continue
 				}
 
-				if err := m.storage.Add(content); err != nil {
+				m.lastContent = content
+				stored, err := m.capture(ctx, content)
+				if err != nil {
+					m.recordError(err)
 					continue
 				}
-				m.lastContent = content
+				if stored {
+					m.recordCapture()
+					m.prune()
+				}
 			}
 		}
 	}
 }
 
+// deleteExpired deletes the items whose expiry time has passed. Nothing is
+// written to storage while paused, so they are kept until capture resumes.
+func (m *Monitor) deleteExpired() {
+	if m.Paused() {
+		return
+	}
+	if err := m.storage.DeleteExpired(); err != nil {
+		m.recordError(err)
+	}
+}
+
+// capture runs the actions of type rules and then the hooks on new
+// clipboard content and stores the result, reporting whether anything was
+// stored. Failing hooks are recorded and skipped, they never prevent the
+// capture.
+func (m *Monitor) capture(ctx context.Context, content string) (bool, error) {
+	item := types.ClipboardItem{
+		Content:   content,
+		Type:      types.DetectType(content),
+		Timestamp: time.Now(),
+	}
+	item.Preview = types.MakePreview(item.Content, item.Type)
+
+	m.mu.RLock()
+	runner, rules := m.hooks, m.typeRules
+	m.mu.RUnlock()
+
+	if rules != nil {
+		var keep bool
+		if item, keep = rules.Apply(item); !keep {
+			return false, nil
+		}
+	}
+
+	if runner != nil {
+		var vetoed bool
+		var errs []error
+		item, vetoed, errs = runner.Run(ctx, item)
+		for _, err := range errs {
+			m.recordError(err)
+		}
+		if vetoed || item.Content == "" {
+			return false, nil
+		}
+	}
+
+	if _, err := m.storage.AddItem(item); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // SetClipboard sets the system clipboard content
 func SetClipboard(content string) error {
 	return clipboard.WriteAll(content)
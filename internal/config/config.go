@@ -1,27 +1,472 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dvd/cliptui/internal/typerules"
+)
+
+// envPrefix is prepended to every environment variable that overrides a setting
+const envPrefix = "CLIPTUI_"
+
+// Source tells where the effective value of a setting came from
+type Source string
+
+// Source constants, in increasing order of precedence
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Values of ui.preview_position
+const (
+	PreviewAuto   = "auto"
+	PreviewRight  = "right"
+	PreviewBottom = "bottom"
+)
+
+// Values of storage.binary
+const (
+	BinaryStore  = "store"
+	BinaryReject = "reject"
 )
 
 // Config holds application configuration
 type Config struct {
-	DBPath      string
-	MaxItems    int
-	PollInterval int // milliseconds
+	Storage   StorageConfig   `toml:"storage"`
+	Monitor   MonitorConfig   `toml:"monitor"`
+	Retention RetentionConfig `toml:"retention"`
+	UI        UIConfig        `toml:"ui"`
+	Hooks     HooksConfig     `toml:"hooks"`
+
+	// Types are user-defined item types, detected before the built-in ones
+	Types []typerules.Rule `toml:"types"`
+
+	// Keybindings maps a mode (list, preview, search) to action names and the
+	// keys bound to them
+	Keybindings map[string]map[string][]string `toml:"keybindings"`
+
+	// File is the config file that was loaded, empty if none was found
+	File string `toml:"-"`
+
+	sources map[string]Source
+}
+
+// StorageConfig holds database and daemon file locations
+type StorageConfig struct {
+	DBPath     string `toml:"db_path"`
+	SocketPath string `toml:"socket_path"`
+	PIDPath    string `toml:"pid_path"`
+	// Binary decides what `cliptui add` does with binary input: store or reject
+	Binary string `toml:"binary"`
+}
+
+// MonitorConfig holds clipboard monitor settings
+type MonitorConfig struct {
+	PollInterval time.Duration `toml:"poll_interval"`
+	// Ignore lists regular expressions; matching clipboard content is never stored
+	Ignore []string `toml:"ignore"`
+}
+
+// RetentionConfig controls how much history is kept
+type RetentionConfig struct {
+	MaxItems int           `toml:"max_items"` // 0 keeps everything
+	MaxAge   time.Duration `toml:"max_age"`   // 0 keeps everything
+}
+
+// UIConfig holds TUI settings
+type UIConfig struct {
+	ListLimit int  `toml:"list_limit"`
+	Mouse     bool `toml:"mouse"`
+	// Theme is a built-in theme or the name of a file in ThemesDir
+	Theme string `toml:"theme"`
+	// SearchMode is the search mode the TUI starts in: fuzzy, exact or regex
+	SearchMode string `toml:"search_mode"`
+	// PreviewPane shows a live preview of the selected item next to the list
+	PreviewPane bool `toml:"preview_pane"`
+	// PreviewPosition places the preview pane: right of the list, below it,
+	// or auto to pick by terminal width
+	PreviewPosition string `toml:"preview_position"`
+	// PreviewRatio is the percentage of the width or height the pane takes
+	PreviewRatio int `toml:"preview_ratio"`
+	// StayOpen keeps the TUI open after copying, for running it in a pane
+	StayOpen bool `toml:"stay_open"`
+	// LineNumbers starts the lines of the preview with their numbers
+	LineNumbers bool `toml:"line_numbers"`
+	// Wrap wraps the long lines of the preview instead of scrolling them
+	Wrap bool `toml:"wrap"`
+}
+
+// HooksConfig controls the executables run on every capture
+type HooksConfig struct {
+	Dir     string        `toml:"dir"`
+	Timeout time.Duration `toml:"timeout"` // per hook
 }
 
 // Default returns default configuration
 func Default() *Config {
-	homeDir, _ := os.UserHomeDir()
-	dataDir := filepath.Join(homeDir, ".local", "share", "cliptui")
+	dataDir := DataDir()
 
 	os.MkdirAll(dataDir, 0755)
 
 	return &Config{
-		DBPath:      filepath.Join(dataDir, "clipboard.db"),
-		MaxItems:    1000,
-		PollInterval: 500,
+		Storage: StorageConfig{
+			DBPath:     filepath.Join(dataDir, "clipboard.db"),
+			SocketPath: filepath.Join(runtimeDir(), "cliptui.sock"),
+			PIDPath:    filepath.Join(runtimeDir(), "cliptui.pid"),
+			Binary:     BinaryReject,
+		},
+		Monitor: MonitorConfig{
+			PollInterval: 500 * time.Millisecond,
+		},
+		Retention: RetentionConfig{
+			MaxItems: 1000,
+		},
+		UI: UIConfig{
+			ListLimit:       100,
+			Mouse:           true,
+			Theme:           "dark",
+			SearchMode:      "fuzzy",
+			PreviewPosition: PreviewAuto,
+			PreviewRatio:    50,
+			LineNumbers:     true,
+			Wrap:            true,
+		},
+		Hooks: HooksConfig{
+			Dir:     filepath.Join(ConfigDir(), "hooks"),
+			Timeout: 2 * time.Second,
+		},
+	}
+}
+
+// Load builds the effective configuration: defaults, overridden by the config
+// file at path, overridden by CLIPTUI_* environment variables. An empty path
+// loads DefaultPath if it exists. Command-line flags are applied by the caller.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+	}
+
+	md, err := toml.DecodeFile(path, cfg)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			path = ""
+		} else {
+			return nil, fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	cfg.File = path
+
+	for _, f := range fields(cfg) {
+		if md.IsDefined(strings.Split(f.Key, ".")...) {
+			cfg.SetSource(f.Key, SourceFile)
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	cfg.Storage.DBPath = ExpandHome(cfg.Storage.DBPath)
+	cfg.Storage.SocketPath = ExpandHome(cfg.Storage.SocketPath)
+	cfg.Storage.PIDPath = ExpandHome(cfg.Storage.PIDPath)
+	cfg.Hooks.Dir = ExpandHome(cfg.Hooks.Dir)
+
+	return cfg, nil
+}
+
+// SetSource records where the setting with the given dotted key came from
+func (c *Config) SetSource(key string, source Source) {
+	if c.sources == nil {
+		c.sources = make(map[string]Source)
+	}
+	c.sources[key] = source
+}
+
+// Source returns where the setting with the given dotted key came from
+func (c *Config) Source(key string) Source {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// Setting is the effective value of a single setting, formatted as TOML
+type Setting struct {
+	Key    string
+	Value  string
+	Source Source
+}
+
+// Settings lists every effective setting with the source of its value
+func (c *Config) Settings() []Setting {
+	var settings []Setting
+	for _, f := range fields(c) {
+		settings = append(settings, Setting{
+			Key:    f.Key,
+			Value:  formatValue(f.Value),
+			Source: c.Source(f.Key),
+		})
+	}
+
+	for _, mode := range sortedKeys(c.Keybindings) {
+		for _, action := range sortedKeys(c.Keybindings[mode]) {
+			settings = append(settings, Setting{
+				Key:    "keybindings." + mode + "." + action,
+				Value:  formatValue(reflect.ValueOf(c.Keybindings[mode][action])),
+				Source: SourceFile,
+			})
+		}
+	}
+	for _, rule := range c.Types {
+		prefix := "types." + rule.Name + "."
+		if rule.Pattern != "" {
+			settings = append(settings, Setting{Key: prefix + "pattern", Value: strconv.Quote(rule.Pattern), Source: SourceFile})
+		}
+		if rule.Predicate != "" {
+			settings = append(settings, Setting{Key: prefix + "predicate", Value: strconv.Quote(rule.Predicate), Source: SourceFile})
+		}
+		settings = append(settings, Setting{Key: prefix + "priority", Value: strconv.Itoa(rule.Priority), Source: SourceFile})
+		color := rule.Color.Tag()
+		if color == "-" {
+			color = "default"
+		}
+		settings = append(settings, Setting{Key: prefix + "color", Value: strconv.Quote(color), Source: SourceFile})
+		if len(rule.Actions) > 0 {
+			settings = append(settings, Setting{Key: prefix + "actions", Value: formatValue(reflect.ValueOf(rule.Actions)), Source: SourceFile})
+		}
+	}
+	return settings
+}
+
+// formatValue renders a setting as a TOML value
+func formatValue(v reflect.Value) string {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return strconv.Quote(d.String())
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = formatValue(v.Index(i))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// sortedKeys returns the keys of a string-keyed map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ExpandHome replaces a leading ~/ with the user's home directory
+func ExpandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[2:])
+}
+
+// TypeRules compiles the user-defined types
+func (c *Config) TypeRules() (*typerules.Set, error) {
+	rules, err := typerules.New(c.Types)
+	if err != nil {
+		return nil, fmt.Errorf("types: %w", err)
+	}
+	return rules, nil
+}
+
+// IgnorePatterns compiles the monitor's ignore expressions
+func (c *Config) IgnorePatterns() ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(c.Monitor.Ignore))
+	for _, expr := range c.Monitor.Ignore {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("monitor.ignore: %w", err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// DefaultPath returns $XDG_CONFIG_HOME/cliptui/config.toml
+func DefaultPath() string {
+	return filepath.Join(ConfigDir(), "config.toml")
+}
+
+// ConfigDir returns $XDG_CONFIG_HOME/cliptui, defaulting to ~/.config/cliptui
+func ConfigDir() string {
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "cliptui")
+}
+
+// ThemesDir returns the directory holding user theme files
+func ThemesDir() string {
+	return filepath.Join(ConfigDir(), "themes")
+}
+
+// DataDir returns $XDG_DATA_HOME/cliptui, defaulting to ~/.local/share/cliptui
+func DataDir() string {
+	return filepath.Join(xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")), "cliptui")
+}
+
+// StateDir returns $XDG_STATE_HOME/cliptui, defaulting to ~/.local/state/cliptui
+func StateDir() string {
+	return filepath.Join(xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state")), "cliptui")
+}
+
+// DebugLogPath returns the default debug log file in the state directory
+func DebugLogPath() string {
+	return filepath.Join(StateDir(), "debug.log")
+}
+
+// xdgDir returns the directory named by env, or fallback relative to the home directory
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, fallback)
+}
+
+// runtimeDir returns $XDG_RUNTIME_DIR, falling back to a per-user directory
+// in the temp directory when no runtime dir is set
+func runtimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("cliptui-%d", os.Getuid()))
+	os.MkdirAll(dir, 0700)
+	return dir
+}
+
+// field is a single scalar setting, addressed by its dotted TOML key
+type field struct {
+	Key   string
+	Value reflect.Value
+}
+
+// EnvName returns the environment variable that overrides the field,
+// e.g. monitor.poll_interval is CLIPTUI_MONITOR_POLL_INTERVAL
+func (f field) EnvName() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(f.Key, ".", "_"))
+}
+
+// fields lists every scalar setting of cfg in declaration order
+func fields(cfg *Config) []field {
+	var out []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			tag := t.Field(i).Tag.Get("toml")
+			if tag == "" || tag == "-" {
+				continue
+			}
+			fv := v.Field(i)
+			switch fv.Kind() {
+			case reflect.Struct:
+				walk(fv, prefix+tag+".")
+			case reflect.Map:
+				// Tables of arbitrary keys are only set from the file
+			case reflect.Slice:
+				// So are arrays of tables
+				if fv.Type().Elem().Kind() == reflect.Struct {
+					continue
+				}
+				out = append(out, field{Key: prefix + tag, Value: fv})
+			default:
+				out = append(out, field{Key: prefix + tag, Value: fv})
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return out
+}
+
+// applyEnv overrides settings from CLIPTUI_* environment variables
+func applyEnv(cfg *Config) error {
+	for _, f := range fields(cfg) {
+		raw, ok := os.LookupEnv(f.EnvName())
+		if !ok {
+			continue
+		}
+		if err := setValue(f.Value, raw); err != nil {
+			return fmt.Errorf("%s: %w", f.EnvName(), err)
+		}
+		cfg.SetSource(f.Key, SourceEnv)
+	}
+	return nil
+}
+
+// setValue parses raw into the setting held by v
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		if raw != "" {
+			items = strings.Split(raw, ",")
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
 	}
+	return nil
 }
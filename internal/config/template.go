@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// defaultFile is written by WriteDefault. Every setting is commented out so
+// that the built-in defaults keep applying until the user changes them.
+const defaultFile = `# clipTUI configuration
+#
+# Settings are resolved as: command-line flags > CLIPTUI_* environment
+# variables > this file > built-in defaults. Every setting can be set from
+# the environment, e.g. monitor.poll_interval is CLIPTUI_MONITOR_POLL_INTERVAL.
+# Send SIGHUP to the daemon to reload this file without restarting it.
+
+[storage]
+# SQLite database holding the history (default: $XDG_DATA_HOME/cliptui/clipboard.db)
+# db_path = "~/.local/share/cliptui/clipboard.db"
+
+# Socket the daemon listens on (default: $XDG_RUNTIME_DIR/cliptui.sock)
+# socket_path = "/run/user/1000/cliptui.sock"
+
+# Lock file holding the daemon's PID (default: $XDG_RUNTIME_DIR/cliptui.pid)
+# pid_path = "/run/user/1000/cliptui.pid"
+
+# What 'cliptui add' does with binary input (NUL bytes or invalid UTF-8):
+# "store" keeps it as a binary item, "reject" refuses it
+# binary = "reject"
+
+[monitor]
+# How often the clipboard is checked
+# poll_interval = "500ms"
+
+# Regular expressions; clipboard content matching any of them is never stored
+# ignore = ['^sk-[A-Za-z0-9]{20,}$']
+
+[retention]
+# Maximum number of items to keep, 0 keeps everything. Pinned items are always kept.
+# max_items = 1000
+
+# Delete items older than this, 0 keeps everything
+# max_age = "720h"
+
+[ui]
+# Number of items loaded into the TUI
+# list_limit = 100
+
+# Capture mouse events for scrolling
+# mouse = true
+
+# Color theme: dark, light, high-contrast, no-color or the name of a file in
+# ~/.config/cliptui/themes. NO_COLOR=1 in the environment always wins.
+# theme = "dark"
+
+# Search mode the TUI starts in: fuzzy, exact or regex. Searches ignore case
+# unless the query contains an uppercase letter.
+# search_mode = "fuzzy"
+
+# Show a live preview of the selected item next to the list at startup; the
+# toggle_preview key (tab) shows and hides it
+# preview_pane = false
+
+# Where the preview pane goes: right of the list, bottom, or auto to put it
+# on the right in terminals at least 120 columns wide and below otherwise
+# preview_position = "auto"
+
+# Percentage of the width (right) or height (bottom) taken by the preview pane
+# preview_ratio = 50
+
+# Keep the TUI open after copying an item, which moves the item to the top,
+# instead of quitting. The toggle_stay_open key (S) switches it, and the
+# copy_quit key (Y) always quits.
+# stay_open = false
+
+# Number the lines of the preview; the line_numbers key (#) switches it
+# line_numbers = true
+
+# Wrap long lines in the preview instead of scrolling them sideways; the
+# toggle_wrap key (w) switches it
+# wrap = true
+
+[hooks]
+# Executables in this directory run on every capture, in name order, with the
+# item as JSON on stdin. They may print {"veto": true} to drop the capture, or
+# {"content": "...", "type": "code", "tags": ["work"]} to change it.
+# dir = "~/.config/cliptui/hooks"
+
+# How long a single hook may run before it is killed and skipped
+# timeout = "2s"
+
+# Custom types for content the built-in detection does not know. Each rule
+# needs a name, a regular expression matched against the trimmed content, a
+# search query the content must match ("predicate"), or both. Rules are tried
+# before the built-in types, highest priority first. Items of a custom type
+# get a badge in the list and can be found with type:<name>. Actions run when
+# an item is captured: pin, skip, tag:<tag> and expire:<duration>.
+#
+# [[types]]
+# name = "ticket"
+# pattern = '^[A-Z]{2,10}-\d+$'
+# color = "orange"
+# actions = ["tag:work"]
+#
+# [[types]]
+# name = "host"
+# pattern = '^[\w-]+\.corp\.example\.com$'
+# priority = 10
+
+# Keybindings per mode (list, preview, search). Each action takes a list of
+# keys such as "y", "enter", "ctrl+p", "alt+x" or sequences like "gg" and
+# "ctrl+w j". Listing an action replaces its default keys; an empty list
+# unbinds it.
+#
+# List actions: quick_copy, up, down, top, bottom, copy, preview,
+#   toggle_preview, search, mark, visual, invert_selection, clear_selection,
+#   pin, tag, export, join, delete, clear, toggle_capture, copy_quit,
+#   toggle_stay_open, quit
+# Preview actions: copy, copy_quit, scroll_up, scroll_down, top, bottom,
+#   language, toggle_view, line_numbers, fold, unfold, search, next_match,
+#   prev_match, toggle_wrap, goto_line, back
+# Search actions: confirm, cancel, toggle_mode
+#
+# [keybindings.list]
+# copy = ["y", "enter"]
+# clear = []
+`
+
+// WriteDefault writes a commented default config file to path, refusing to
+// replace an existing file unless force is set
+func WriteDefault(path string, force bool) error {
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(defaultFile), 0644)
+}
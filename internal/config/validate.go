@@ -0,0 +1,249 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dvd/cliptui/internal/keymap"
+	"github.com/dvd/cliptui/internal/search"
+	"github.com/dvd/cliptui/internal/theme"
+	"github.com/dvd/cliptui/internal/typerules"
+)
+
+// decodeErrorLine extracts the line number from decode errors such as
+// `toml: line 3 (last key "m.n"): incompatible types`
+var decodeErrorLine = regexp.MustCompile(`^toml: line (\d+)`)
+
+// Problem is a single issue found while validating a config
+type Problem struct {
+	Line    int // 0 when the line is unknown
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	var b strings.Builder
+	if p.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", p.Line)
+	}
+	if p.Key != "" {
+		fmt.Fprintf(&b, "%s: ", p.Key)
+	}
+	b.WriteString(p.Message)
+	return b.String()
+}
+
+// ValidationError reports every problem found in a config
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = "  " + p.String()
+	}
+
+	source := "configuration"
+	if e.File != "" {
+		source = e.File
+	}
+	return fmt.Sprintf("invalid %s:\n%s", source, strings.Join(lines, "\n"))
+}
+
+// ValidateFile checks the config file at path for syntax errors, type
+// errors, unknown keys and invalid values, with line numbers where possible
+func ValidateFile(path string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+
+	cfg := Default()
+	md, err := toml.Decode(text, cfg)
+	if err != nil {
+		return []Problem{decodeProblem(err)}, nil
+	}
+
+	var problems []Problem
+	undecoded := make(map[string]bool)
+	for _, key := range md.Undecoded() {
+		undecoded[key.String()] = true
+	}
+	for _, key := range md.Undecoded() {
+		// Report an unknown table once rather than once per key inside it
+		if len(key) > 1 && undecoded[toml.Key(key[:len(key)-1]).String()] {
+			continue
+		}
+		problems = append(problems, Problem{
+			Key:     key.String(),
+			Message: "unknown key",
+		})
+	}
+
+	problems = append(problems, cfg.Validate()...)
+
+	for i := range problems {
+		problems[i].Line = findKeyLine(text, problems[i].Key)
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
+
+// decodeProblem converts a TOML decoding error into a Problem
+func decodeProblem(err error) Problem {
+	var parseErr toml.ParseError
+	if errors.As(err, &parseErr) {
+		return Problem{Line: parseErr.Position.Line, Message: parseErr.Message}
+	}
+
+	message := err.Error()
+	if m := decodeErrorLine.FindStringSubmatch(message); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		// Drop the "toml: line N" prefix, it is reported separately
+		if i := strings.Index(message, "): "); i >= 0 {
+			message = message[i+3:]
+		}
+		return Problem{Line: line, Message: message}
+	}
+	return Problem{Message: message}
+}
+
+// minPollInterval keeps the monitor from checking the clipboard so often
+// that it takes a whole CPU
+const minPollInterval = 50 * time.Millisecond
+
+// Validate checks that the effective settings are usable
+func (c *Config) Validate() []Problem {
+	var problems []Problem
+	add := func(key, format string, args ...any) {
+		problems = append(problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if c.Storage.DBPath == "" {
+		add("storage.db_path", "must not be empty")
+	}
+	if c.Storage.Binary != BinaryStore && c.Storage.Binary != BinaryReject {
+		add("storage.binary", "must be %q or %q", BinaryStore, BinaryReject)
+	}
+	if c.Monitor.PollInterval < minPollInterval {
+		// A bare number in TOML is read as nanoseconds
+		add("monitor.poll_interval", "must be at least %s, written as a duration like \"500ms\"", minPollInterval)
+	}
+	for _, expr := range c.Monitor.Ignore {
+		if _, err := regexp.Compile(expr); err != nil {
+			add("monitor.ignore", "invalid regular expression %q: %v", expr, err)
+		}
+	}
+	if c.Retention.MaxItems < 0 {
+		add("retention.max_items", "must not be negative")
+	}
+	if c.Retention.MaxAge < 0 {
+		add("retention.max_age", "must not be negative")
+	}
+	if c.UI.ListLimit <= 0 {
+		add("ui.list_limit", "must be greater than zero")
+	}
+	if c.Hooks.Timeout <= 0 {
+		add("hooks.timeout", "must be greater than zero")
+	}
+	if _, err := theme.Load(c.UI.Theme, ThemesDir()); err != nil {
+		add("ui.theme", "%v", err)
+	}
+	if _, err := search.ParseMode(c.UI.SearchMode); err != nil {
+		add("ui.search_mode", "must be fuzzy, exact or regex")
+	}
+	switch c.UI.PreviewPosition {
+	case PreviewAuto, PreviewRight, PreviewBottom:
+	default:
+		add("ui.preview_position", "must be %q, %q or %q", PreviewAuto, PreviewRight, PreviewBottom)
+	}
+	if c.UI.PreviewRatio < 10 || c.UI.PreviewRatio > 90 {
+		add("ui.preview_ratio", "must be between 10 and 90")
+	}
+	if _, err := typerules.New(c.Types); err != nil {
+		add("types", "%v", err)
+	}
+
+	for _, mode := range sortedKeys(c.Keybindings) {
+		if !isKeybindingMode(mode) {
+			add("keybindings."+mode, "unknown mode, expected one of %s", strings.Join(keymap.Modes(), ", "))
+			continue
+		}
+		before := len(problems)
+		for _, action := range sortedKeys(c.Keybindings[mode]) {
+			if !keymap.HasAction(mode, action) {
+				add("keybindings."+mode+"."+action, "unknown action")
+				continue
+			}
+			for _, spec := range c.Keybindings[mode][action] {
+				if _, err := keymap.Parse(spec); err != nil {
+					add("keybindings."+mode+"."+action, "invalid key %q: %v", spec, err)
+				}
+			}
+		}
+		// With every key valid, only a conflict between actions is left
+		if len(problems) == before {
+			if _, err := keymap.New(mode, c.Keybindings[mode]); err != nil {
+				add("keybindings", "%v", err)
+			}
+		}
+	}
+
+	return problems
+}
+
+// isKeybindingMode reports whether mode accepts keybindings
+func isKeybindingMode(mode string) bool {
+	for _, m := range keymap.Modes() {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// findKeyLine returns the line on which a dotted key or table is defined in
+// a TOML document, or 0 if it cannot be found
+func findKeyLine(text, key string) int {
+	if key == "" {
+		return 0
+	}
+	parts := strings.Split(key, ".")
+	table := strings.Join(parts[:len(parts)-1], ".")
+	leaf := parts[len(parts)-1]
+
+	current := ""
+	for i, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			if end := strings.Index(trimmed, "]"); end > 0 {
+				trimmed = trimmed[:end]
+			}
+			current = strings.TrimSpace(strings.TrimLeft(trimmed, "["))
+			if current == key || strings.HasPrefix(current, key+".") {
+				return i + 1
+			}
+			continue
+		}
+		if current != table || !strings.HasPrefix(trimmed, leaf) {
+			continue
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(trimmed, leaf))
+		if strings.HasPrefix(rest, "=") {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// AlreadyRunningError is returned by AcquireLock when another daemon holds the lock
+type AlreadyRunningError struct {
+	PID  int
+	Path string
+}
+
+func (e *AlreadyRunningError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("daemon is already running (pid %d, lock %s)", e.PID, e.Path)
+	}
+	return fmt.Sprintf("daemon is already running (lock %s)", e.Path)
+}
+
+// Lock is an exclusive single-instance lock backed by a PID file
+type Lock struct {
+	file *os.File
+	path string
+}
+
+// AcquireLock takes the lock at path and records the current PID in it.
+// The lock is released automatically by the kernel if the process dies.
+func AcquireLock(path string) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, &AlreadyRunningError{PID: ReadPID(path), Path: path}
+		}
+		return nil, err
+	}
+
+	if err := file.Truncate(0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &Lock{file: file, path: path}, nil
+}
+
+// Release removes the PID file and drops the lock
+func (l *Lock) Release() error {
+	os.Remove(l.path)
+	return l.file.Close()
+}
+
+// ReadPID returns the PID recorded in the lock file at path, or 0 if unknown
+func ReadPID(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+// Output formats
+const (
+	FormatTable  = "table"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatTSV    = "tsv"
+)
+
+// Formats lists every supported output format
+var Formats = []string{FormatTable, FormatJSON, FormatNDJSON, FormatTSV}
+
+// tablePreviewLength is the width of the content column in table output
+const tablePreviewLength = 60
+
+// tsvEscaper keeps each TSV record on a single line
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// Options control how items are written
+type Options struct {
+	Format string
+	// Null terminates records with a NUL byte instead of a newline. TSV content
+	// is then written unescaped, so multi-line items survive unchanged.
+	Null bool
+}
+
+// Write writes items to w in the requested format
+func Write(w io.Writer, items []types.ClipboardItem, opts Options) error {
+	if opts.Null && opts.Format != FormatNDJSON && opts.Format != FormatTSV {
+		return fmt.Errorf("--null only works with the ndjson and tsv formats")
+	}
+
+	switch opts.Format {
+	case FormatTable, "":
+		return writeTable(w, items)
+	case FormatJSON:
+		return writeJSON(w, items)
+	case FormatNDJSON:
+		return writeNDJSON(w, items, terminator(opts.Null))
+	case FormatTSV:
+		return writeTSV(w, items, opts.Null)
+	}
+	return fmt.Errorf("unknown format %q, expected one of %s", opts.Format, strings.Join(Formats, ", "))
+}
+
+// terminator returns the record separator
+func terminator(null bool) string {
+	if null {
+		return "\x00"
+	}
+	return "\n"
+}
+
+// writeTable writes an aligned table for reading in a terminal
+func writeTable(w io.Writer, items []types.ClipboardItem) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTYPE\tPINNED\tTIME\tCONTENT")
+	for _, item := range items {
+		pinned := ""
+		if item.Pinned {
+			pinned = "yes"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n",
+			item.ID,
+			item.Type,
+			pinned,
+			item.Timestamp.Format("2006-01-02 15:04"),
+			types.SingleLine(item.Preview, tablePreviewLength))
+	}
+	return tw.Flush()
+}
+
+// writeJSON writes a single indented JSON array
+func writeJSON(w io.Writer, items []types.ClipboardItem) error {
+	if items == nil {
+		items = []types.ClipboardItem{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(items)
+}
+
+// writeNDJSON writes one JSON object per record
+func writeNDJSON(w io.Writer, items []types.ClipboardItem, term string) error {
+	for _, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, string(data)+term); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTSV writes id, timestamp, type, pinned and content separated by tabs.
+// Content comes last so that a raw, NUL-terminated record can still be split
+// on the first four tabs.
+func writeTSV(w io.Writer, items []types.ClipboardItem, null bool) error {
+	for _, item := range items {
+		content := item.Content
+		if !null {
+			content = tsvEscaper.Replace(content)
+		}
+		_, err := fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s%s",
+			item.ID,
+			item.Timestamp.Format(time.RFC3339),
+			item.Type,
+			strconv.FormatBool(item.Pinned),
+			content,
+			terminator(null))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
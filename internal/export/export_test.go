@@ -0,0 +1,103 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// testItems covers the content each format has to escape or keep intact:
+// tabs, newlines, backslashes, quotes, unicode and empty content
+var testItems = []types.ClipboardItem{
+	{
+		ID:        3,
+		Content:   "https://example.com/search?q=clip",
+		Type:      types.TypeURL,
+		Timestamp: time.Date(2024, 5, 17, 9, 30, 0, 0, time.UTC),
+		Preview:   "https://example.com/search?q=clip",
+		Pinned:    true,
+		Tags:      []string{"work"},
+	},
+	{
+		ID:        2,
+		Content:   "func main() {\n\tfmt.Println(\"héllo\\n\")\n}",
+		Type:      types.TypeCode,
+		Timestamp: time.Date(2024, 5, 16, 18, 5, 42, 0, time.UTC),
+		Preview:   "func main() { fmt.Println(\"héllo\\n\") }",
+		Language:  "Go",
+	},
+	{
+		ID:        1,
+		Content:   "",
+		Type:      types.TypeText,
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		ExpiresAt: time.Date(2024, 1, 3, 3, 4, 5, 0, time.UTC),
+	},
+}
+
+func TestWriteGolden(t *testing.T) {
+	tests := []struct {
+		golden string
+		opts   Options
+	}{
+		{"table.golden", Options{Format: FormatTable}},
+		{"json.golden", Options{Format: FormatJSON}},
+		{"ndjson.golden", Options{Format: FormatNDJSON}},
+		{"tsv.golden", Options{Format: FormatTSV}},
+		{"ndjson-null.golden", Options{Format: FormatNDJSON, Null: true}},
+		{"tsv-null.golden", Options{Format: FormatTSV, Null: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, testItems, tt.opts); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("output differs from %s:\n got: %q\nwant: %q", path, buf.Bytes(), want)
+			}
+		})
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, nil, Options{Format: FormatJSON}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("empty JSON = %q, want %q", got, "[]\n")
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	tests := []Options{
+		{Format: "xml"},
+		{Format: FormatTable, Null: true},
+		{Format: FormatJSON, Null: true},
+	}
+	for _, opts := range tests {
+		if err := Write(&bytes.Buffer{}, testItems, opts); err == nil {
+			t.Errorf("Write with %+v succeeded, want an error", opts)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/dvd/cliptui/pkg/client"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+// waitDelay bounds how long a hook that has been killed, or has exited while
+// a child still holds its output open, may keep the capture waiting
+const waitDelay = 200 * time.Millisecond
+
+// maxOutput caps how much a hook may print on stdout, and on stderr, before
+// it is killed and its output rejected
+const maxOutput = 16 << 20
+
+// errOutputTooLarge reports a hook that printed more than maxOutput bytes
+var errOutputTooLarge = fmt.Errorf("output larger than %d bytes", maxOutput)
+
+// Result is what a hook may print on stdout as JSON. Empty output leaves the
+// item unchanged.
+type Result struct {
+	// Veto drops the capture; later hooks do not run
+	Veto bool `json:"veto"`
+	// Content replaces the item's content when set
+	Content *string `json:"content"`
+	// Data replaces the item's content when set, base64 encoded, for
+	// content that is not valid UTF-8
+	Data []byte `json:"data"`
+	// Type replaces the item's type when set
+	Type string `json:"type"`
+	// Tags are added to the item's tags
+	Tags []string `json:"tags"`
+}
+
+// Error reports a hook that failed. The capture goes on without its changes.
+type Error struct {
+	Hook string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("hook %s: %v", e.Hook, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Runner runs the executables in a directory on every capture
+type Runner struct {
+	dir     string
+	timeout time.Duration
+}
+
+// NewRunner creates a runner for the hooks in dir, each limited to timeout
+func NewRunner(dir string, timeout time.Duration) *Runner {
+	return &Runner{dir: dir, timeout: timeout}
+}
+
+// Hooks lists the executables in the hooks directory in the order they run,
+// sorted by name. A missing directory has no hooks.
+func (r *Runner) Hooks() ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+			continue
+		}
+		// Follow symlinks so hooks can be linked in from elsewhere
+		info, err := os.Stat(filepath.Join(r.dir, name))
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		hooks = append(hooks, filepath.Join(r.dir, name))
+	}
+	sort.Strings(hooks)
+	return hooks, nil
+}
+
+// Run passes item through every hook in turn, each one seeing the changes of
+// the ones before it. It reports whether a hook vetoed the capture. Failing
+// hooks are skipped and returned as errors; they never stop the capture.
+// Run returns once every hook is done, so the monitor does not check the
+// clipboard while a slow hook runs, for up to its timeout.
+func (r *Runner) Run(ctx context.Context, item types.ClipboardItem) (types.ClipboardItem, bool, []error) {
+	hooks, err := r.Hooks()
+	if err != nil {
+		return item, false, []error{err}
+	}
+
+	var errs []error
+	for _, hook := range hooks {
+		result, err := r.runHook(ctx, hook, item)
+		if err != nil {
+			errs = append(errs, &Error{Hook: filepath.Base(hook), Err: err})
+			continue
+		}
+		if result == nil {
+			continue
+		}
+		if result.Veto {
+			return item, true, errs
+		}
+		item = apply(item, result)
+	}
+	return item, false, errs
+}
+
+// runHook runs a single hook with the item as JSON on stdin, encoded like
+// items sent to the daemon so that binary content reaches it intact
+func (r *Runner) runHook(ctx context.Context, hook string, item types.ClipboardItem) (*Result, error) {
+	input, err := json.Marshal(client.NewItem(item))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	// Cancelling kills the hook as soon as it prints too much, rather than
+	// collecting its output until it stops
+	var tooLarge atomic.Bool
+	exceeded := func() {
+		tooLarge.Store(true)
+		cancel()
+	}
+	stdout := &cappedBuffer{limit: maxOutput, exceeded: exceeded}
+	stderr := &cappedBuffer{limit: maxOutput, exceeded: exceeded}
+
+	cmd := exec.CommandContext(ctx, hook)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = waitDelay
+	// Run each hook in its own process group so a timeout also kills
+	// anything it started
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	err = cmd.Run()
+	if tooLarge.Load() {
+		return nil, errOutputTooLarge
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("timed out after %s", r.timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	out := bytes.TrimSpace(stdout.Bytes())
+	if len(out) == 0 {
+		return nil, nil
+	}
+	var result Result
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("invalid output: %w", err)
+	}
+	if result.Content != nil && result.Data != nil {
+		return nil, fmt.Errorf("invalid output: both content and data are set")
+	}
+	if result.Type != "" && !types.KnownType(result.Type) {
+		return nil, fmt.Errorf("unknown type %q", result.Type)
+	}
+	for _, tag := range result.Tags {
+		if err := types.ValidateTag(tag); err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+// cappedBuffer collects up to limit bytes of output. A write past the limit
+// fails and calls exceeded. The buffer is not embedded, so that copying into
+// it cannot bypass Write through bytes.Buffer.ReadFrom.
+type cappedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	exceeded func()
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > b.limit {
+		b.exceeded()
+		return 0, errOutputTooLarge
+	}
+	return b.buf.Write(p)
+}
+
+// Bytes returns the output collected
+func (b *cappedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// String returns the output collected as a string
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
+
+// apply merges a hook's result into the item
+func apply(item types.ClipboardItem, result *Result) types.ClipboardItem {
+	content := result.Content
+	if result.Data != nil {
+		data := string(result.Data)
+		content = &data
+	}
+	if content != nil && *content != item.Content {
+		item.Content = *content
+		item.Preview = ""
+		if result.Type == "" {
+			item.Type = types.DetectType(item.Content)
+		}
+	}
+	if result.Type != "" {
+		item.Type = result.Type
+		item.Preview = ""
+	}
+	for _, tag := range result.Tags {
+		if !slices.Contains(item.Tags, tag) {
+			item.Tags = append(item.Tags, tag)
+		}
+	}
+	return item
+}
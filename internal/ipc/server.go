@@ -0,0 +1,359 @@
+package ipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dvd/cliptui/internal/clipboard"
+	"github.com/dvd/cliptui/internal/storage"
+	"github.com/dvd/cliptui/pkg/client"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+// subscriberBuffer is how many events may queue up for a slow subscriber
+// before further events are dropped for it
+const subscriberBuffer = 64
+
+// Server exposes the daemon's storage and monitor over a Unix socket
+type Server struct {
+	store     *storage.Storage
+	monitor   *clipboard.Monitor
+	startedAt time.Time
+	socket    string
+	shutdown  func()
+
+	mu   sync.Mutex
+	subs map[chan types.Event]struct{}
+}
+
+// NewServer creates a server for the given storage and monitor
+func NewServer(store *storage.Storage, monitor *clipboard.Monitor) *Server {
+	return &Server{
+		store:     store,
+		monitor:   monitor,
+		startedAt: time.Now(),
+		subs:      make(map[chan types.Event]struct{}),
+	}
+}
+
+// OnShutdown registers the function called when a client asks the daemon to stop
+func (s *Server) OnShutdown(fn func()) {
+	s.shutdown = fn
+}
+
+// Serve listens on the socket at path until ctx is cancelled
+func (s *Server) Serve(ctx context.Context, path string) error {
+	if err := removeStaleSocket(path); err != nil {
+		return err
+	}
+	s.socket = path
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	// Only the owner may talk to the daemon
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go s.handleConn(ctx, conn)
+	}
+}
+
+// removeStaleSocket deletes a socket file left behind by a daemon that is no
+// longer running, and fails if another daemon is still listening on it
+func removeStaleSocket(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("another daemon is already listening on %s", path)
+	}
+	return os.Remove(path)
+}
+
+// Publish forwards a history change to every subscriber
+func (s *Server) Publish(event types.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subs {
+		select {
+		case ch <- event:
+		default:
+			// Subscriber is not keeping up, drop the event rather than block storage
+		}
+	}
+}
+
+// handleConn serves requests from a single client until it disconnects
+func (s *Server) handleConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	enc := json.NewEncoder(conn)
+
+	for {
+		var req client.Request
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+
+		if req.Method == client.MethodShutdown {
+			enc.Encode(client.Response{ID: req.ID})
+			if s.shutdown != nil {
+				s.shutdown()
+			}
+			return
+		}
+
+		if req.Method == client.MethodSubscribe {
+			if err := enc.Encode(client.Response{ID: req.ID}); err != nil {
+				return
+			}
+			s.stream(ctx, conn, enc)
+			return
+		}
+
+		resp := client.Response{ID: req.ID}
+		result, err := s.dispatch(req)
+		if err != nil {
+			resp.Error = err.Error()
+		} else if result != nil {
+			raw, err := json.Marshal(result)
+			if err != nil {
+				resp.Error = err.Error()
+			} else {
+				resp.Result = raw
+			}
+		}
+
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// stream writes history changes to a subscribed connection until it closes
+func (s *Server) stream(ctx context.Context, conn net.Conn, enc *json.Encoder) {
+	events := make(chan types.Event, subscriberBuffer)
+
+	s.mu.Lock()
+	s.subs[events] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, events)
+		s.mu.Unlock()
+	}()
+
+	// Subscribers never send anything else, so a read only returns once the
+	// client has gone away
+	closed := make(chan struct{})
+	go func() {
+		conn.Read(make([]byte, 1))
+		close(closed)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-closed:
+			return
+		case event := <-events:
+			if err := enc.Encode(client.NewEvent(event)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// dispatch executes a single request and returns its result
+func (s *Server) dispatch(req client.Request) (any, error) {
+	switch req.Method {
+	case client.MethodList:
+		var params client.ListParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		if params.Limit <= 0 {
+			return wireItems(s.store.GetAll())
+		}
+		return wireItems(s.store.GetRecent(params.Limit))
+
+	case client.MethodQuery:
+		var q types.Query
+		if err := decodeParams(req, &q); err != nil {
+			return nil, err
+		}
+		return wireItems(s.store.List(q))
+
+	case client.MethodGet:
+		var params client.IDParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return wireItem(s.store.Get(params.ID))
+
+	case client.MethodLatest:
+		return wireItem(s.store.GetLatest())
+
+	case client.MethodAdd:
+		var params client.AddParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.store.Add(string(params.Content))
+
+	case client.MethodAddItem:
+		var item client.Item
+		if err := decodeParams(req, &item); err != nil {
+			return nil, err
+		}
+		return wireItem(s.store.AddItem(item.Unwrap()))
+
+	case client.MethodDelete:
+		var params client.IDParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.store.Delete(params.ID)
+
+	case client.MethodClear:
+		return nil, s.store.Clear()
+
+	case client.MethodPin:
+		var params client.PinParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.store.SetPinned(params.ID, params.Pinned)
+
+	case client.MethodSetType:
+		var params client.SetTypeParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.store.SetType(params.ID, params.Type)
+
+	case client.MethodSetLanguage:
+		var params client.SetLanguageParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.store.SetLanguage(params.ID, params.Language)
+
+	case client.MethodSetTags:
+		var params client.SetTagsParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.store.SetTags(params.ID, params.Tags)
+
+	case client.MethodTouch:
+		var params client.IDParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.store.Touch(params.ID)
+
+	case client.MethodSearch:
+		var params client.SearchParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return wireItems(s.store.Search(params.Query, params.Limit))
+
+	case client.MethodPause:
+		var params client.PauseParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		s.monitor.Pause(params.Duration)
+		return nil, nil
+
+	case client.MethodResume:
+		s.monitor.Resume()
+		return nil, nil
+
+	case client.MethodStatus:
+		return s.status(), nil
+	}
+
+	return nil, fmt.Errorf("unknown method %q", req.Method)
+}
+
+// wireItem prepares an item returned by the store to be sent to the client
+func wireItem(item *types.ClipboardItem, err error) (any, error) {
+	if err != nil || item == nil {
+		return nil, err
+	}
+	return client.NewItem(*item), nil
+}
+
+// wireItems prepares items returned by the store to be sent to the client
+func wireItems(items []types.ClipboardItem, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	return client.NewItems(items), nil
+}
+
+// decodeParams unmarshals the request parameters into v
+func decodeParams(req client.Request, v any) error {
+	if len(req.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		return fmt.Errorf("invalid params for %s: %w", req.Method, err)
+	}
+	return nil
+}
+
+// status collects the daemon's current state
+func (s *Server) status() *client.Status {
+	stats := s.monitor.Stats()
+	status := &client.Status{
+		PID:         os.Getpid(),
+		StartedAt:   s.startedAt,
+		Captures:    stats.Captures,
+		LastCapture: stats.LastCapture,
+		Backend:     clipboard.Backend(),
+		Database:    s.store.Path(),
+		Socket:      s.socket,
+		Paused:      stats.Paused,
+		PausedUntil: stats.PausedUntil,
+	}
+	if stats.LastError != nil {
+		status.LastError = stats.LastError.Error()
+	}
+	return status
+}
@@ -0,0 +1,247 @@
+package keymap
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// modifierMask holds the modifiers that distinguish one binding from another.
+// Shift is left out because it is already reflected in the rune of printable keys.
+const modifierMask = tcell.ModCtrl | tcell.ModAlt | tcell.ModMeta
+
+// Key is a single key press, optionally with modifiers
+type Key struct {
+	Key  tcell.Key
+	Rune rune
+	Mod  tcell.ModMask
+}
+
+// Sequence is one or more keys pressed in order, like "gg"
+type Sequence []Key
+
+// namedKeys maps the names accepted in bindings to tcell keys
+var namedKeys = map[string]tcell.Key{
+	"enter":     tcell.KeyEnter,
+	"esc":       tcell.KeyEscape,
+	"escape":    tcell.KeyEscape,
+	"tab":       tcell.KeyTab,
+	"backtab":   tcell.KeyBacktab,
+	"backspace": tcell.KeyBackspace2,
+	"delete":    tcell.KeyDelete,
+	"del":       tcell.KeyDelete,
+	"insert":    tcell.KeyInsert,
+	"up":        tcell.KeyUp,
+	"down":      tcell.KeyDown,
+	"left":      tcell.KeyLeft,
+	"right":     tcell.KeyRight,
+	"home":      tcell.KeyHome,
+	"end":       tcell.KeyEnd,
+	"pgup":      tcell.KeyPgUp,
+	"pageup":    tcell.KeyPgUp,
+	"pgdn":      tcell.KeyPgDn,
+	"pagedown":  tcell.KeyPgDn,
+	"f1":        tcell.KeyF1,
+	"f2":        tcell.KeyF2,
+	"f3":        tcell.KeyF3,
+	"f4":        tcell.KeyF4,
+	"f5":        tcell.KeyF5,
+	"f6":        tcell.KeyF6,
+	"f7":        tcell.KeyF7,
+	"f8":        tcell.KeyF8,
+	"f9":        tcell.KeyF9,
+	"f10":       tcell.KeyF10,
+	"f11":       tcell.KeyF11,
+	"f12":       tcell.KeyF12,
+}
+
+// keyNames is the preferred display name for each named key
+var keyNames = map[tcell.Key]string{
+	tcell.KeyEnter:      "enter",
+	tcell.KeyEscape:     "esc",
+	tcell.KeyTab:        "tab",
+	tcell.KeyBacktab:    "backtab",
+	tcell.KeyBackspace2: "backspace",
+	tcell.KeyDelete:     "del",
+	tcell.KeyInsert:     "insert",
+	tcell.KeyUp:         "↑",
+	tcell.KeyDown:       "↓",
+	tcell.KeyLeft:       "←",
+	tcell.KeyRight:      "→",
+	tcell.KeyHome:       "home",
+	tcell.KeyEnd:        "end",
+	tcell.KeyPgUp:       "pgup",
+	tcell.KeyPgDn:       "pgdn",
+}
+
+// Parse reads a binding such as "y", "ctrl+p", "enter", "gg" or "ctrl+w j".
+// Keys separated by spaces form a sequence, and so does a word of plain
+// characters that is not a key name.
+func Parse(spec string) (Sequence, error) {
+	tokens := strings.Fields(spec)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty key binding")
+	}
+
+	var seq Sequence
+	for _, token := range tokens {
+		keys, err := parseToken(token)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, keys...)
+	}
+	return seq, nil
+}
+
+// parseToken parses a single whitespace-free part of a binding
+func parseToken(token string) ([]Key, error) {
+	if utf8.RuneCountInString(token) == 1 {
+		r, _ := utf8.DecodeRuneInString(token)
+		return []Key{{Key: tcell.KeyRune, Rune: r}}, nil
+	}
+
+	lower := strings.ToLower(token)
+	if key, ok := namedKeys[lower]; ok {
+		return []Key{{Key: key}}, nil
+	}
+	if lower == "space" {
+		return []Key{{Key: tcell.KeyRune, Rune: ' '}}, nil
+	}
+
+	if strings.Contains(token, "+") && !strings.HasSuffix(token, "+") {
+		key, err := parseModified(token)
+		if err != nil {
+			return nil, err
+		}
+		return []Key{key}, nil
+	}
+
+	// A plain word like "gg" is a sequence of its characters
+	var keys []Key
+	for _, r := range token {
+		keys = append(keys, Key{Key: tcell.KeyRune, Rune: r})
+	}
+	return keys, nil
+}
+
+// parseModified parses a key with modifiers such as "ctrl+p" or "alt+enter"
+func parseModified(token string) (Key, error) {
+	parts := strings.Split(token, "+")
+	base := parts[len(parts)-1]
+
+	var mod tcell.ModMask
+	for _, name := range parts[:len(parts)-1] {
+		switch strings.ToLower(name) {
+		case "ctrl", "control":
+			mod |= tcell.ModCtrl
+		case "alt":
+			mod |= tcell.ModAlt
+		case "meta", "super":
+			mod |= tcell.ModMeta
+		case "shift":
+			mod |= tcell.ModShift
+		default:
+			return Key{}, fmt.Errorf("unknown modifier %q in %q", name, token)
+		}
+	}
+
+	keys, err := parseToken(base)
+	if err != nil {
+		return Key{}, err
+	}
+	if len(keys) != 1 {
+		return Key{}, fmt.Errorf("unknown key %q in %q", base, token)
+	}
+	key := keys[0]
+
+	// Terminals report ctrl+letter as a dedicated control key
+	if mod&tcell.ModCtrl != 0 && key.Key == tcell.KeyRune {
+		r := key.Rune
+		if r >= 'A' && r <= 'Z' {
+			r += 'a' - 'A'
+		}
+		if r < 'a' || r > 'z' {
+			return Key{}, fmt.Errorf("ctrl can only be combined with letters, got %q", token)
+		}
+		return Key{Key: tcell.KeyCtrlA + tcell.Key(r-'a'), Mod: mod}, nil
+	}
+
+	if mod&tcell.ModShift != 0 && key.Key == tcell.KeyRune {
+		return Key{}, fmt.Errorf("use the uppercase character instead of shift in %q", token)
+	}
+
+	key.Mod = mod
+	return key, nil
+}
+
+// FromEvent converts a terminal key event into a Key
+func FromEvent(event *tcell.EventKey) Key {
+	return Key{Key: event.Key(), Rune: event.Rune(), Mod: event.Modifiers()}
+}
+
+// Matches reports whether two keys are the same press
+func (k Key) Matches(other Key) bool {
+	if k.Key != other.Key {
+		return false
+	}
+	if k.Key == tcell.KeyRune && k.Rune != other.Rune {
+		return false
+	}
+	if k.Key >= tcell.KeyCtrlA && k.Key <= tcell.KeyCtrlZ {
+		return true
+	}
+
+	mask := modifierMask
+	if k.Key != tcell.KeyRune {
+		mask |= tcell.ModShift
+	}
+	return k.Mod&mask == other.Mod&mask
+}
+
+// String returns the binding syntax for the key
+func (k Key) String() string {
+	var prefix string
+	if k.Mod&tcell.ModMeta != 0 {
+		prefix += "meta+"
+	}
+	if k.Mod&tcell.ModAlt != 0 {
+		prefix += "alt+"
+	}
+	if k.Mod&tcell.ModShift != 0 && k.Key != tcell.KeyRune {
+		prefix += "shift+"
+	}
+
+	// Named keys come first, since tab and enter share codes with ctrl+i and ctrl+m
+	if name, ok := keyNames[k.Key]; ok {
+		return prefix + name
+	}
+
+	switch {
+	case k.Key >= tcell.KeyCtrlA && k.Key <= tcell.KeyCtrlZ:
+		return prefix + "ctrl+" + string(rune('a'+k.Key-tcell.KeyCtrlA))
+	case k.Key == tcell.KeyRune && k.Rune == ' ':
+		return prefix + "space"
+	case k.Key == tcell.KeyRune:
+		return prefix + string(k.Rune)
+	}
+	return prefix + tcell.KeyNames[k.Key]
+}
+
+// String returns the binding syntax for the sequence
+func (s Sequence) String() string {
+	parts := make([]string, len(s))
+	plain := true
+	for i, key := range s {
+		parts[i] = key.String()
+		if key.Key != tcell.KeyRune || key.Mod != 0 || key.Rune == ' ' {
+			plain = false
+		}
+	}
+	if plain {
+		return strings.Join(parts, "")
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,259 @@
+package keymap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Modes that accept keybindings
+const (
+	ModeList    = "list"
+	ModePreview = "preview"
+	ModeSearch  = "search"
+)
+
+// Action describes a bindable TUI command and its default keys
+type Action struct {
+	Name        string
+	Description string // short label shown in the help bar
+	Keys        []string
+}
+
+// actions lists every bindable action per mode, in help bar order
+var actions = map[string][]Action{
+	ModeList: {
+		{Name: "quick_copy", Description: "quick copy", Keys: []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}},
+		{Name: "up", Description: "up", Keys: []string{"up", "k"}},
+		{Name: "down", Description: "down", Keys: []string{"down", "j"}},
+		{Name: "top", Description: "top", Keys: []string{"gg", "home"}},
+		{Name: "bottom", Description: "bottom", Keys: []string{"G", "end"}},
+		{Name: "copy", Description: "copy", Keys: []string{"enter", "y"}},
+		{Name: "preview", Description: "preview", Keys: []string{"p"}},
+		{Name: "toggle_preview", Description: "preview pane", Keys: []string{"tab"}},
+		{Name: "search", Description: "search", Keys: []string{"/"}},
+		{Name: "mark", Description: "select", Keys: []string{"space"}},
+		{Name: "visual", Description: "select range", Keys: []string{"V"}},
+		{Name: "invert_selection", Description: "invert selection", Keys: []string{"*"}},
+		{Name: "clear_selection", Description: "clear selection", Keys: []string{"esc"}},
+		{Name: "pin", Description: "pin", Keys: []string{"P"}},
+		{Name: "tag", Description: "tag", Keys: []string{"t"}},
+		{Name: "export", Description: "export", Keys: []string{"e"}},
+		{Name: "join", Description: "join and copy", Keys: []string{"J"}},
+		{Name: "delete", Description: "delete", Keys: []string{"d"}},
+		{Name: "clear", Description: "clear", Keys: []string{"D"}},
+		{Name: "toggle_capture", Description: "pause capture", Keys: []string{"i"}},
+		{Name: "copy_quit", Description: "copy and quit", Keys: []string{"Y"}},
+		{Name: "toggle_stay_open", Description: "stay open", Keys: []string{"S"}},
+		{Name: "quit", Description: "quit", Keys: []string{"q"}},
+	},
+	ModePreview: {
+		{Name: "copy", Description: "copy", Keys: []string{"enter", "y"}},
+		{Name: "copy_quit", Description: "copy and quit", Keys: []string{"Y"}},
+		{Name: "scroll_up", Description: "up", Keys: []string{"up", "k"}},
+		{Name: "scroll_down", Description: "down", Keys: []string{"down", "j"}},
+		{Name: "top", Description: "top", Keys: []string{"gg", "home"}},
+		{Name: "bottom", Description: "bottom", Keys: []string{"G", "end"}},
+		{Name: "language", Description: "language", Keys: []string{"L"}},
+		{Name: "toggle_view", Description: "raw/rendered", Keys: []string{"r"}},
+		{Name: "line_numbers", Description: "line numbers", Keys: []string{"#"}},
+		{Name: "fold", Description: "fold JSON", Keys: []string{"-"}},
+		{Name: "unfold", Description: "unfold JSON", Keys: []string{"+", "="}},
+		{Name: "search", Description: "search", Keys: []string{"/"}},
+		{Name: "next_match", Description: "next match", Keys: []string{"n"}},
+		{Name: "prev_match", Description: "previous match", Keys: []string{"N"}},
+		{Name: "toggle_wrap", Description: "wrap", Keys: []string{"w"}},
+		{Name: "goto_line", Description: "go to line", Keys: []string{":"}},
+		{Name: "back", Description: "back", Keys: []string{"esc", "q"}},
+	},
+	ModeSearch: {
+		{Name: "confirm", Description: "confirm", Keys: []string{"enter"}},
+		{Name: "cancel", Description: "cancel", Keys: []string{"esc"}},
+		{Name: "toggle_mode", Description: "fuzzy/exact/regex", Keys: []string{"ctrl+r"}},
+	},
+}
+
+// Modes returns the names of all modes that accept keybindings
+func Modes() []string {
+	modes := make([]string, 0, len(actions))
+	for mode := range actions {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	return modes
+}
+
+// Actions returns the bindable actions of a mode
+func Actions(mode string) []Action {
+	return actions[mode]
+}
+
+// HasAction reports whether mode has an action with the given name
+func HasAction(mode, name string) bool {
+	for _, a := range actions[mode] {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Binding binds a key sequence to an action
+type Binding struct {
+	Action string
+	Keys   Sequence
+}
+
+// sequenceTimeout is how long a partially typed sequence waits for its next
+// key before it is dropped
+const sequenceTimeout = time.Second
+
+// Keymap resolves key presses to actions for a single mode
+type Keymap struct {
+	mode     string
+	bindings []Binding
+	pending  Sequence
+	// pendingAt is when the last key of pending was pressed
+	pendingAt time.Time
+	now       func() time.Time
+}
+
+// New builds the keymap of a mode from its default bindings, with the keys
+// of any action listed in overrides replaced. An empty list unbinds the action.
+// It fails when two actions end up bound to the same keys.
+func New(mode string, overrides map[string][]string) (*Keymap, error) {
+	if _, ok := actions[mode]; !ok {
+		return nil, fmt.Errorf("unknown mode %q", mode)
+	}
+	for name := range overrides {
+		if !HasAction(mode, name) {
+			return nil, fmt.Errorf("unknown %s action %q", mode, name)
+		}
+	}
+
+	m := &Keymap{mode: mode, now: time.Now}
+	for _, a := range actions[mode] {
+		specs := a.Keys
+		if keys, ok := overrides[a.Name]; ok {
+			specs = keys
+		}
+		for _, spec := range specs {
+			seq, err := Parse(spec)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", mode, a.Name, err)
+			}
+			for _, b := range m.bindings {
+				if b.Action != a.Name && b.Keys.hasPrefix(seq) && len(b.Keys) == len(seq) {
+					return nil, fmt.Errorf("%s.%s: %s is also bound to %s", mode, a.Name, seq, b.Action)
+				}
+			}
+			m.bindings = append(m.bindings, Binding{Action: a.Name, Keys: seq})
+		}
+	}
+	return m, nil
+}
+
+// Feed processes a key press. It returns the action that was triggered, if
+// any, and whether the key was used, either by an action or as the start of
+// a longer sequence. When a binding is a prefix of another, the shorter one
+// wins. A sequence left unfinished for sequenceTimeout starts over.
+func (m *Keymap) Feed(key Key) (action string, consumed bool) {
+	now := m.now()
+	if len(m.pending) > 0 && now.Sub(m.pendingAt) > sequenceTimeout {
+		m.pending = nil
+	}
+	m.pending = append(m.pending, key)
+	m.pendingAt = now
+
+	prefix := false
+	for _, b := range m.bindings {
+		if !b.Keys.hasPrefix(m.pending) {
+			continue
+		}
+		if len(b.Keys) == len(m.pending) {
+			m.pending = nil
+			return b.Action, true
+		}
+		prefix = true
+	}
+	if prefix {
+		return "", true
+	}
+
+	// The sequence went nowhere; start over with just this key
+	if len(m.pending) > 1 {
+		m.pending = nil
+		return m.Feed(key)
+	}
+	m.pending = nil
+	return "", false
+}
+
+// Reset discards a partially typed sequence
+func (m *Keymap) Reset() {
+	m.pending = nil
+}
+
+// Keys returns the sequences bound to an action
+func (m *Keymap) Keys(action string) []Sequence {
+	var keys []Sequence
+	for _, b := range m.bindings {
+		if b.Action == action {
+			keys = append(keys, b.Keys)
+		}
+	}
+	return keys
+}
+
+// Help describes the bound actions, e.g. "0-9 quick copy • ↑/k up • q quit"
+func (m *Keymap) Help() string {
+	var parts []string
+	for _, a := range actions[m.mode] {
+		keys := m.Keys(a.Name)
+		if len(keys) == 0 {
+			continue
+		}
+		parts = append(parts, formatKeys(keys)+" "+a.Description)
+	}
+	return strings.Join(parts, " • ")
+}
+
+// formatKeys joins the sequences of an action, collapsing a run of
+// consecutive single characters such as 0..9 into "0-9"
+func formatKeys(keys []Sequence) string {
+	if len(keys) > 2 {
+		consecutive := true
+		for i, seq := range keys {
+			if len(seq) != 1 || seq[0].Key != tcell.KeyRune || seq[0].Mod != 0 ||
+				(i > 0 && seq[0].Rune != keys[i-1][0].Rune+1) {
+				consecutive = false
+				break
+			}
+		}
+		if consecutive {
+			return keys[0].String() + "-" + keys[len(keys)-1].String()
+		}
+	}
+
+	names := make([]string, len(keys))
+	for i, seq := range keys {
+		names[i] = seq.String()
+	}
+	return strings.Join(names, "/")
+}
+
+// hasPrefix reports whether s starts with prefix
+func (s Sequence) hasPrefix(prefix Sequence) bool {
+	if len(prefix) > len(s) {
+		return false
+	}
+	for i, key := range prefix {
+		if !s[i].Matches(key) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,294 @@
+package keymap
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// r is an unmodified printable key
+func r(c rune) Key {
+	return Key{Key: tcell.KeyRune, Rune: c}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Sequence
+	}{
+		{"y", Sequence{r('y')}},
+		{"G", Sequence{r('G')}},
+		{"+", Sequence{r('+')}},
+		{"space", Sequence{r(' ')}},
+		{"enter", Sequence{{Key: tcell.KeyEnter}}},
+		{"Esc", Sequence{{Key: tcell.KeyEscape}}},
+		{"pagedown", Sequence{{Key: tcell.KeyPgDn}}},
+
+		// Modifiers
+		{"ctrl+d", Sequence{{Key: tcell.KeyCtrlD, Mod: tcell.ModCtrl}}},
+		{"Ctrl+D", Sequence{{Key: tcell.KeyCtrlD, Mod: tcell.ModCtrl}}},
+		{"control+p", Sequence{{Key: tcell.KeyCtrlP, Mod: tcell.ModCtrl}}},
+		{"alt+x", Sequence{{Key: tcell.KeyRune, Rune: 'x', Mod: tcell.ModAlt}}},
+		{"alt+enter", Sequence{{Key: tcell.KeyEnter, Mod: tcell.ModAlt}}},
+		{"meta+x", Sequence{{Key: tcell.KeyRune, Rune: 'x', Mod: tcell.ModMeta}}},
+		{"shift+tab", Sequence{{Key: tcell.KeyTab, Mod: tcell.ModShift}}},
+		{"ctrl+alt+k", Sequence{{Key: tcell.KeyCtrlK, Mod: tcell.ModCtrl | tcell.ModAlt}}},
+
+		// Sequences
+		{"gg", Sequence{r('g'), r('g')}},
+		{"g g", Sequence{r('g'), r('g')}},
+		{"ctrl+w j", Sequence{{Key: tcell.KeyCtrlW, Mod: tcell.ModCtrl}, r('j')}},
+		{"z enter", Sequence{r('z'), {Key: tcell.KeyEnter}}},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		err  string // part of the message
+	}{
+		{"", "empty key binding"},
+		{"   ", "empty key binding"},
+		{"hyper+x", `unknown modifier "hyper"`},
+		{"ctrl+1", "ctrl can only be combined with letters"},
+		{"ctrl+enterr", `unknown key "enterr"`},
+		{"shift+a", "use the uppercase character"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.spec)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Parse(%q) error = %v, want it to contain %q", tt.spec, err, tt.err)
+		}
+	}
+}
+
+func TestSequenceString(t *testing.T) {
+	for _, spec := range []string{"y", "gg", "space", "enter", "ctrl+d", "alt+x", "ctrl+w j", "shift+tab", "meta+alt+pgup"} {
+		seq, err := Parse(spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", spec, err)
+		}
+		again, err := Parse(seq.String())
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", seq.String(), err)
+		}
+		if !reflect.DeepEqual(again, seq) {
+			t.Errorf("Parse(%q) = %#v, want %#v", seq.String(), again, seq)
+		}
+	}
+	if got := (Sequence{{Key: tcell.KeyUp}}).String(); got != "↑" {
+		t.Errorf("String() = %q, want ↑", got)
+	}
+}
+
+func TestKeyMatches(t *testing.T) {
+	tests := []struct {
+		binding string
+		event   Key
+		want    bool
+	}{
+		{"y", r('y'), true},
+		{"y", r('Y'), false},
+		// Shift is part of the rune of printable keys
+		{"Y", Key{Key: tcell.KeyRune, Rune: 'Y', Mod: tcell.ModShift}, true},
+		{"y", Key{Key: tcell.KeyRune, Rune: 'y', Mod: tcell.ModAlt}, false},
+		{"alt+y", Key{Key: tcell.KeyRune, Rune: 'y', Mod: tcell.ModAlt}, true},
+		// Terminals do not always report ctrl with control keys
+		{"ctrl+d", Key{Key: tcell.KeyCtrlD}, true},
+		{"ctrl+d", Key{Key: tcell.KeyCtrlD, Mod: tcell.ModCtrl}, true},
+		{"tab", Key{Key: tcell.KeyTab}, true},
+		{"tab", Key{Key: tcell.KeyTab, Mod: tcell.ModShift}, false},
+		{"up", Key{Key: tcell.KeyDown}, false},
+	}
+
+	for _, tt := range tests {
+		seq, err := Parse(tt.binding)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.binding, err)
+		}
+		if got := seq[0].Matches(tt.event); got != tt.want {
+			t.Errorf("%q matches %#v = %v, want %v", tt.binding, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		mode      string
+		overrides map[string][]string
+		err       string // part of the message
+	}{
+		{"nope", nil, `unknown mode "nope"`},
+		{ModeList, map[string][]string{"explode": {"x"}}, `unknown list action "explode"`},
+		{ModeList, map[string][]string{"pin": {"ctrl+1"}}, "list.pin"},
+		// The default binding of delete is still d
+		{ModeList, map[string][]string{"pin": {"d"}}, "list.delete: d is also bound to pin"},
+		{ModePreview, map[string][]string{"search": {"ctrl+w j"}, "back": {"ctrl+w j"}}, "preview.back: ctrl+w j is also bound to search"},
+	}
+
+	for _, tt := range tests {
+		_, err := New(tt.mode, tt.overrides)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("New(%s, %v) error = %v, want it to contain %q", tt.mode, tt.overrides, err, tt.err)
+		}
+	}
+}
+
+func TestDefaultKeymaps(t *testing.T) {
+	for _, mode := range Modes() {
+		if _, err := New(mode, nil); err != nil {
+			t.Errorf("New(%s): %v", mode, err)
+		}
+	}
+	// Moving an action out of the way resolves a conflict
+	if _, err := New(ModeList, map[string][]string{"pin": {"d"}, "delete": {"ctrl+d"}}); err != nil {
+		t.Errorf("New with swapped keys: %v", err)
+	}
+}
+
+// feed is a key press in TestFeed, after wait since the one before
+type feed struct {
+	key      Key
+	wait     time.Duration
+	action   string
+	consumed bool
+}
+
+func TestFeed(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		overrides map[string][]string
+		feeds     []feed
+	}{
+		{"single key", ModeList, nil, []feed{
+			{key: r('j'), action: "down", consumed: true},
+			{key: r('k'), action: "up", consumed: true},
+		}},
+		{"unbound key", ModeList, nil, []feed{
+			{key: r('x')},
+		}},
+		{"sequence", ModeList, nil, []feed{
+			{key: r('g'), consumed: true},
+			{key: r('g'), action: "top", consumed: true},
+		}},
+		{"sequence that goes nowhere starts over", ModeList, nil, []feed{
+			{key: r('g'), consumed: true},
+			{key: r('j'), action: "down", consumed: true},
+		}},
+		{"sequence that goes nowhere starts over unbound", ModeList, nil, []feed{
+			{key: r('g'), consumed: true},
+			{key: r('x')},
+			{key: r('g'), consumed: true},
+		}},
+		{"sequence times out", ModeList, nil, []feed{
+			{key: r('g'), consumed: true},
+			{key: r('g'), wait: 2 * time.Second, consumed: true},
+			{key: r('g'), wait: 100 * time.Millisecond, action: "top", consumed: true},
+		}},
+		{"shorter binding wins", ModeList, map[string][]string{"search": {"g"}}, []feed{
+			{key: r('g'), action: "search", consumed: true},
+			{key: r('g'), action: "search", consumed: true},
+		}},
+		{"modified keys", ModePreview, map[string][]string{"next_match": {"ctrl+n"}, "prev_match": {"alt+n"}}, []feed{
+			{key: Key{Key: tcell.KeyCtrlN, Mod: tcell.ModCtrl}, action: "next_match", consumed: true},
+			{key: Key{Key: tcell.KeyRune, Rune: 'n', Mod: tcell.ModAlt}, action: "prev_match", consumed: true},
+			{key: r('n')},
+		}},
+		{"multi-key sequence with a modifier", ModePreview, map[string][]string{"back": {"ctrl+w q"}}, []feed{
+			{key: Key{Key: tcell.KeyCtrlW}, consumed: true},
+			{key: r('q'), action: "back", consumed: true},
+		}},
+		{"per-mode bindings", ModeSearch, nil, []feed{
+			{key: r('j')},
+			{key: Key{Key: tcell.KeyEnter}, action: "confirm", consumed: true},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.mode, tt.overrides)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			m.now = func() time.Time { return now }
+
+			for i, f := range tt.feeds {
+				now = now.Add(f.wait)
+				action, consumed := m.Feed(f.key)
+				if action != f.action || consumed != f.consumed {
+					t.Errorf("key %d (%s): Feed = %q, %v, want %q, %v", i, Sequence{f.key}, action, consumed, f.action, f.consumed)
+				}
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	m, err := New(ModeList, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Feed(r('g'))
+	m.Reset()
+	if action, consumed := m.Feed(r('G')); action != "bottom" || !consumed {
+		t.Errorf("Feed(G) after Reset = %q, %v, want bottom", action, consumed)
+	}
+	m.Feed(r('g'))
+	m.Reset()
+	if action, _ := m.Feed(r('g')); action != "" {
+		t.Errorf("Feed(g) after Reset = %q, want the start of a sequence", action)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	tests := []struct {
+		mode      string
+		overrides map[string][]string
+		want      string
+	}{
+		{ModeSearch, nil, "enter confirm • esc cancel • ctrl+r fuzzy/exact/regex"},
+		{ModeSearch, map[string][]string{"confirm": {"ctrl+j", "tab"}, "toggle_mode": {}},
+			"ctrl+j/tab confirm • esc cancel"},
+	}
+
+	for _, tt := range tests {
+		m, err := New(tt.mode, tt.overrides)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Help(); got != tt.want {
+			t.Errorf("Help() = %q, want %q", got, tt.want)
+		}
+	}
+
+	m, err := New(ModeList, map[string][]string{"quick_copy": {"1", "2", "3"}, "top": {"home"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	help := m.Help()
+	for _, want := range []string{"1-3 quick copy • ↑/k up", "home top •"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("Help() = %q, want it to contain %q", help, want)
+		}
+	}
+	if strings.Contains(help, "gg") {
+		t.Errorf("Help() = %q, want the unbound gg left out", help)
+	}
+}
@@ -0,0 +1,188 @@
+package language
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+// minKeywordScore is how many distinct keyword patterns of a language must
+// match before keywords alone decide it
+const minKeywordScore = 2
+
+// headerLines is how many lines at the top are searched for shebangs,
+// modelines and file names
+const headerLines = 3
+
+var (
+	// shebang matches the interpreter, and the command run by env after its
+	// options, as in "#!/usr/bin/env -S deno run"
+	shebang = regexp.MustCompile(`^#!\s*(?:\S*/)?([\w.+-]+)(?:(?:\s+-\S+)*\s+([\w.+-]+))?`)
+	// modeline matches vim and emacs file type hints like "vim: ft=python"
+	modeline = regexp.MustCompile(`(?:vim?:.*\b(?:ft|filetype|syntax)=([\w+-]+))|(?:-\*-.*mode:\s*([\w+-]+))`)
+	// fileName matches a file name in a comment, like "// main.go"
+	fileName = regexp.MustCompile(`^\s*(?://|#|--|;|/\*|<!--)\s*(?:file:\s*)?([\w./-]+\.\w{1,6})\b`)
+)
+
+// interpreters maps shebang interpreters that are not chroma aliases to a
+// language
+var interpreters = map[string]string{
+	"node":    "javascript",
+	"nodejs":  "javascript",
+	"bun":     "javascript",
+	"deno":    "typescript",
+	"ts-node": "typescript",
+}
+
+// keywords are patterns that are distinctive for a language, by chroma
+// lexer name. A language wins when the most of its patterns match.
+var keywords = map[string][]*regexp.Regexp{
+	"Go":         compile(`(?m)^package \w+$`, `\bfunc (\(\w+ \*?\w+\) )?\w+\(`, `:= `, `\bfmt\.\w+\(`, `(?m)^import \($`, `\bchan\b|\bgo func\b|\bdefer\b`),
+	"Python":     compile(`(?m)^\s*def \w+\(.*\):\s*$`, `(?m)^\s*(from [\w.]+ )?import \w+`, `\bself\.`, `(?m)^\s*(elif|except)\b.*:\s*$`, `\bprint\(`, `(?m)^if __name__ == .__main__.:`),
+	"Rust":       compile(`\bfn \w+(<.*>)?\(`, `\blet mut\b`, `(?m)^\s*(pub )?(struct|enum|impl|trait|mod) \w+`, `(?m)^use \w+(::\w+)+`, `\w+!\(`, `->\s*(Self|Result|Option|&)`),
+	"JavaScript": compile(`\b(const|let) \w+ = `, `=>\s*[{(]`, `\bfunction\s*\w*\(`, `\bconsole\.\w+\(`, `\brequire\(['"]`, `\bdocument\.|\bwindow\.`),
+	"TypeScript": compile(`\binterface \w+ \{`, `:\s*(string|number|boolean|any|void)\b`, `\btype \w+ = `, `\bimport .* from ['"]`, `<\w+>\(`, `\b(readonly|private|public) \w+:`),
+	"Java":       compile(`\bpublic (static )?(class|void|final)\b`, `\bSystem\.out\.`, `(?m)^import java\.`, `@Override`, `\bnew \w+\(.*\);`, `\bString\[\]`),
+	"C":          compile(`(?m)^#include <\w+\.h>`, `\bprintf\(`, `\bmalloc\(|\bfree\(`, `\bint main\(`, `\bstruct \w+ \{`, `->\w+`),
+	"C++":        compile(`(?m)^#include <\w+>$`, `\bstd::`, `\bcout\s*<<`, `\btemplate\s*<`, `\bnamespace \w+`, `\bclass \w+\s*(:\s*public)?`),
+	"Ruby":       compile(`(?m)^\s*end$`, `(?m)^\s*def \w+[?!]?(\(.*\))?$`, `\bputs\b`, `\bdo \|\w+\|`, `(?m)^\s*require ['"]`, `\battr_(accessor|reader)\b`),
+	"PHP":        compile(`<\?php`, `\$\w+\s*=`, `\becho\b`, `\bfunction \w+\(\$`, `->\w+\(`, `\bnamespace [\w\\]+;`),
+	"SQL":        compile(`(?i)\bselect\b.+\bfrom\b`, `(?i)\binsert into\b`, `(?i)\bcreate (table|index|view)\b`, `(?i)\bwhere\b`, `(?i)\b(inner|left|right) join\b`, `(?i)\bupdate \w+ set\b`),
+	"Bash":       compile(`(?m)^\s*(if|while) \[\[? `, `(?m)^\s*(fi|done|esac)$`, `\$\{?\w+\}?`, `(?m)^\s*export \w+=`, `(?m)^\s*function \w+\s*\{|(?m)^\w+\(\)\s*\{`, `\becho\b`),
+	"Lua":        compile(`\blocal \w+ = `, `(?m)^\s*end$`, `\bfunction \w+[.:]?\w*\(`, `\bthen$`, `\brequire\s*\(?['"]`, `~=`),
+	"Kotlin":     compile(`\bfun \w+\(`, `\bval \w+`, `\bvar \w+:`, `\bdata class\b`, `\bprintln\(`, `\bcompanion object\b`),
+	"Swift":      compile(`\bfunc \w+\(.*\)\s*(->)?`, `\bguard let\b|\bif let\b`, `\bimport (UIKit|Foundation|SwiftUI)\b`, `\bvar \w+: \w+`, `\bstruct \w+: \w+`, `\bprint\(`),
+	"C#":         compile(`\busing System`, `\bnamespace \w+`, `\bpublic (static )?(class|void|async)\b`, `\bConsole\.Write`, `\bvar \w+ = new\b`, `\bstring\[\]`),
+	"Haskell":    compile(`(?m)^\w+ :: `, `(?m)^import qualified\b`, `\bwhere$`, `<-`, `(?m)^module \w+`, `\bdata \w+ = `),
+	"Docker":     compile(`(?m)^FROM \S+`, `(?m)^RUN `, `(?m)^(COPY|ADD) `, `(?m)^(CMD|ENTRYPOINT) `, `(?m)^WORKDIR `, `(?m)^ENV `),
+	"Terraform":  compile(`(?m)^resource "\w+" "\w+"`, `(?m)^variable "\w+"`, `(?m)^provider "\w+"`, `(?m)^output "\w+"`, `(?m)^module "\w+"`, `\bvar\.\w+`),
+	"CSS":        compile(`(?m)^[.#]?[\w-]+(\s*[,>+~]\s*[.#]?[\w-]+)*\s*\{`, `(?m)^\s*[\w-]+:\s*[^;]+;$`, `@media\b`, `\b\d+(px|em|rem|vh|vw)\b`, `#[0-9a-fA-F]{3,6}\b`, `!important`),
+}
+
+// compile compiles keyword patterns
+func compile(patterns ...string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		res[i] = regexp.MustCompile(p)
+	}
+	return res
+}
+
+// Detect guesses the programming language of code and returns the name of
+// its chroma lexer, or "" when it cannot tell. Shebangs, editor modelines
+// and file names in a leading comment are trusted first, then distinctive
+// keywords, then chroma's own analysis.
+func Detect(content string) string {
+	if name := fromHeader(content); name != "" {
+		return name
+	}
+	if name := fromKeywords(content); name != "" {
+		return name
+	}
+	if lexer := lexers.Analyse(content); lexer != nil {
+		return lexerName(lexer)
+	}
+	return ""
+}
+
+// Lookup returns the canonical name of a language given its name, an alias
+// or a file extension, and whether it is known
+func Lookup(language string) (string, bool) {
+	lexer := lexers.Get(strings.TrimSpace(language))
+	if lexer == nil {
+		return "", false
+	}
+	return lexerName(lexer), true
+}
+
+// Names lists every language that can be highlighted
+func Names() []string {
+	names := lexers.Names(false)
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return names
+}
+
+// Lexer returns the chroma lexer of a language, or nil
+func Lexer(language string) chroma.Lexer {
+	if language == "" {
+		return nil
+	}
+	return lexers.Get(language)
+}
+
+// lexerName returns the display name of a lexer
+func lexerName(lexer chroma.Lexer) string {
+	return lexer.Config().Name
+}
+
+// fromHeader reads a shebang, modeline or file name from the first lines
+func fromHeader(content string) string {
+	lines := strings.SplitN(content, "\n", headerLines+1)
+	if len(lines) > headerLines {
+		lines = lines[:headerLines]
+	}
+
+	if m := shebang.FindStringSubmatch(lines[0]); m != nil {
+		interpreter := m[1]
+		if interpreter == "env" && m[2] != "" {
+			interpreter = m[2]
+		}
+		if language, ok := interpreters[interpreter]; ok {
+			interpreter = language
+		}
+		if name, ok := Lookup(interpreter); ok {
+			return name
+		}
+		// python3.12 and the like
+		if name, ok := Lookup(strings.TrimRight(interpreter, "0123456789.")); ok {
+			return name
+		}
+	}
+
+	for _, line := range lines {
+		if m := modeline.FindStringSubmatch(line); m != nil {
+			for _, ft := range m[1:] {
+				if ft == "" {
+					continue
+				}
+				if name, ok := Lookup(ft); ok {
+					return name
+				}
+			}
+		}
+		if m := fileName.FindStringSubmatch(line); m != nil {
+			if lexer := lexers.Match(filepath.Base(m[1])); lexer != nil {
+				return lexerName(lexer)
+			}
+		}
+	}
+	return ""
+}
+
+// fromKeywords returns the language with the most matching keyword patterns
+func fromKeywords(content string) string {
+	best, bestScore := "", 0
+	for language, patterns := range keywords {
+		score := 0
+		for _, re := range patterns {
+			if re.MatchString(content) {
+				score++
+			}
+		}
+		// Break ties by name so the result does not depend on map order
+		if score > bestScore || (score == bestScore && score > 0 && language < best) {
+			best, bestScore = language, score
+		}
+	}
+	if bestScore < minKeywordScore {
+		return ""
+	}
+	name, _ := Lookup(best)
+	return name
+}
@@ -0,0 +1,76 @@
+package language
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		// Shebangs
+		{"bash shebang", "#!/bin/bash\necho hi", "Bash"},
+		{"env shebang", "#!/usr/bin/env python3\nprint('hi')", "Python"},
+		{"versioned interpreter", "#!/usr/bin/python3.12\nx = 1", "Python"},
+		{"node shebang", "#!/usr/bin/env node\nconsole.log(1)", "JavaScript"},
+		{"deno shebang", "#!/usr/bin/env -S deno run\nlet x = 1", "TypeScript"},
+
+		// Modelines
+		{"vim modeline", "# vim: ft=ruby\nx = 1", "Ruby"},
+		{"vim syntax modeline", "x = 1\n// vim: set syntax=rust:", "Rust"},
+		{"emacs modeline", "-- -*- mode: lua -*-\nx = 1", "Lua"},
+
+		// File names in a leading comment
+		{"go file name", "// main.go\nx := 1", "Go"},
+		{"python file name", "# file: tools/build.py\nx = 1", "Python"},
+		{"html comment file name", "<!-- index.html -->\n<p>hi</p>", "HTML"},
+		// The header wins over keywords
+		{"header over keywords", "// notes.rs\nfunc main() {\n\tfmt.Println(x)\n}", "Rust"},
+
+		// Keywords alone
+		{"go keywords", "func main() {\n\tx := 1\n\tfmt.Println(x)\n}", "Go"},
+		{"go package", "package main\n\nimport (\n\t\"os\"\n)", "Go"},
+		{"python keywords", "def greet(name):\n    print(name)\n", "Python"},
+		{"python class", "class A:\n    def run(self):\n        self.x = 1\n", "Python"},
+		{"rust keywords", "fn main() {\n    let mut v = Vec::new();\n    println!(\"{}\", v.len());\n}", "Rust"},
+		{"javascript keywords", "const add = (a, b) => {\n  return a + b;\n};\nconsole.log(add(1, 2));", "JavaScript"},
+		{"typescript keywords", "interface User {\n  name: string;\n}\nconst u: User = { name: \"x\" };", "TypeScript"},
+		{"sql keywords", "SELECT id, name FROM users WHERE active = 1", "SQL"},
+
+		// Nothing to go by
+		{"empty", "", ""},
+		{"prose", "Remember to buy milk on the way home.", ""},
+		{"single keyword", "x := 1", ""},
+		{"unknown shebang", "#!/opt/bin/frobnicate\nhello there", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.content); got != tt.want {
+				t.Errorf("Detect(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+		ok       bool
+	}{
+		{"go", "Go", true},
+		{"golang", "Go", true},
+		{"py", "Python", true},
+		{" rust ", "Rust", true},
+		{"js", "JavaScript", true},
+		{"klingon", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Lookup(tt.language)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.language, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package search
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Scores follow fzf: every matched character earns scoreMatch, gaps between
+// matches cost, and characters that start a word are worth a bonus
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	// bonusBoundary rewards a match right after whitespace or punctuation
+	bonusBoundary = scoreMatch / 2
+	// bonusNonWord rewards matching punctuation itself, which is rarely an accident
+	bonusNonWord = scoreMatch / 2
+	// bonusCamel rewards camelCase humps and the first digit of a number
+	bonusCamel = bonusBoundary + scoreGapExtension
+	// bonusConsecutive makes a run of matches worth at least as much as the
+	// gap it avoids
+	bonusConsecutive = -(scoreGapStart + scoreGapExtension)
+	// bonusFirstCharMultiplier weighs the bonus of the first pattern character
+	bonusFirstCharMultiplier = 2
+)
+
+// charClass groups characters for word boundary detection
+type charClass int
+
+const (
+	charNonWord charClass = iota
+	charLower
+	charUpper
+	charLetter
+	charNumber
+)
+
+// classOf returns the class of r
+func classOf(r rune) charClass {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return charLower
+	case r >= 'A' && r <= 'Z':
+		return charUpper
+	case r >= '0' && r <= '9':
+		return charNumber
+	case r < utf8.RuneSelf:
+		return charNonWord
+	case unicode.IsLower(r):
+		return charLower
+	case unicode.IsUpper(r):
+		return charUpper
+	case unicode.IsLetter(r):
+		return charLetter
+	case unicode.IsNumber(r):
+		return charNumber
+	}
+	return charNonWord
+}
+
+// bonusFor returns the bonus for matching a character of class after one of
+// class prev
+func bonusFor(prev, class charClass) int {
+	switch {
+	case prev == charNonWord && class != charNonWord:
+		return bonusBoundary
+	case prev == charLower && class == charUpper,
+		prev != charNumber && class == charNumber:
+		return bonusCamel
+	case class == charNonWord:
+		return bonusNonWord
+	}
+	return 0
+}
+
+// fold lowercases r unless the search is case sensitive
+func fold(r rune, caseSensitive bool) rune {
+	if caseSensitive {
+		return r
+	}
+	return unicode.ToLower(r)
+}
+
+// fuzzyMatch looks for the characters of pattern in text, in order but not
+// necessarily next to each other. It finds the first place where pattern
+// appears, narrows it down to the shortest window ending there and scores
+// the matches inside that window. Positions are byte offsets into text.
+func fuzzyMatch(text string, pattern []rune, caseSensitive bool) (int, []int, bool) {
+	if len(pattern) == 0 {
+		return 0, nil, true
+	}
+
+	// Forward pass: find where the first complete match ends
+	pi, start, end := 0, 0, -1
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if fold(r, caseSensitive) == pattern[pi] {
+			if pi == 0 {
+				start = i
+			}
+			pi++
+			if pi == len(pattern) {
+				end = i + size
+				break
+			}
+		}
+		i += size
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	// Backward pass: move the start as close to the end as possible
+	pi = len(pattern) - 1
+	for i := end; i > start; {
+		r, size := utf8.DecodeLastRuneInString(text[:i])
+		i -= size
+		if fold(r, caseSensitive) == pattern[pi] {
+			pi--
+			if pi < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	// Score the window, matching greedily from its start
+	score, consecutive, firstBonus := 0, 0, 0
+	inGap := false
+	positions := make([]int, 0, len(pattern))
+	prevClass := classOf(runeBefore(text, start))
+	pi = 0
+	for i := start; i < end && pi < len(pattern); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		class := classOf(r)
+		if fold(r, caseSensitive) == pattern[pi] {
+			positions = append(positions, i)
+			score += scoreMatch
+
+			bonus := bonusFor(prevClass, class)
+			if consecutive == 0 {
+				firstBonus = bonus
+			} else {
+				// A run keeps the bonus of the boundary it started on
+				if bonus >= bonusBoundary && bonus > firstBonus {
+					firstBonus = bonus
+				}
+				bonus = max(bonus, firstBonus, bonusConsecutive)
+			}
+			if pi == 0 {
+				score += bonus * bonusFirstCharMultiplier
+			} else {
+				score += bonus
+			}
+
+			inGap = false
+			consecutive++
+			pi++
+		} else {
+			if inGap {
+				score += scoreGapExtension
+			} else {
+				score += scoreGapStart
+			}
+			inGap = true
+			consecutive = 0
+			firstBonus = 0
+		}
+		prevClass = class
+		i += size
+	}
+	return score, positions, true
+}
@@ -0,0 +1,192 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+func TestFuzzyMatchRanking(t *testing.T) {
+	tests := []struct {
+		name          string
+		pattern       string
+		better, worse string
+	}{
+		{"word boundary", "b", "foo-bar", "fooxbar"},
+		{"after whitespace", "bar", "foo bar", "foobar"},
+		{"start of text", "foo", "foo", "xfoo"},
+		{"camel case", "b", "fooBar", "foobar"},
+		{"camel case humps", "fb", "fooBar", "fxxbxx"},
+		{"first digit", "1", "abc123", "abc213"},
+		{"consecutive", "abc", "xabcx", "xaxbxcx"},
+		{"shorter gap", "ac", "xabcx", "xabbbbcx"},
+		{"boundary run", "bar", "foo_bar", "foobarx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := []rune(tt.pattern)
+			better, _, ok := fuzzyMatch(tt.better, pattern, false)
+			if !ok {
+				t.Fatalf("%q does not match %q", tt.pattern, tt.better)
+			}
+			worse, _, ok := fuzzyMatch(tt.worse, pattern, false)
+			if !ok {
+				t.Fatalf("%q does not match %q", tt.pattern, tt.worse)
+			}
+			if better <= worse {
+				t.Errorf("%q scores %d in %q, want more than %d in %q", tt.pattern, better, tt.better, worse, tt.worse)
+			}
+		})
+	}
+}
+
+func TestFuzzyMatchPositions(t *testing.T) {
+	tests := []struct {
+		text, pattern string
+		want          []int
+	}{
+		{"hello", "hlo", []int{0, 2, 4}},
+		// é and ö take two bytes each
+		{"héllo wörld", "hw", []int{0, 7}},
+		{"héllo wörld", "öl", []int{8, 11}},
+		{"👩‍💻 dev", "dv", []int{12, 14}},
+		// The shortest window ending at the first match is scored
+		{"a_a_b", "ab", []int{2, 4}},
+		{"", "", nil},
+	}
+
+	for _, tt := range tests {
+		_, positions, ok := fuzzyMatch(tt.text, []rune(tt.pattern), false)
+		if !ok {
+			t.Errorf("%q does not match %q", tt.pattern, tt.text)
+			continue
+		}
+		if !slices.Equal(positions, tt.want) {
+			t.Errorf("positions of %q in %q = %v, want %v", tt.pattern, tt.text, positions, tt.want)
+		}
+		for i, pos := range positions {
+			if r := []rune(tt.text[pos:])[0]; r != []rune(tt.pattern)[i] {
+				t.Errorf("position %d of %q in %q is %q, want %q", pos, tt.pattern, tt.text, r, []rune(tt.pattern)[i])
+			}
+		}
+	}
+}
+
+func TestFuzzyMatchMisses(t *testing.T) {
+	tests := []struct{ text, pattern string }{
+		{"hello", "hx"},
+		{"hello", "olh"}, // out of order
+		{"", "a"},
+	}
+	for _, tt := range tests {
+		if _, _, ok := fuzzyMatch(tt.text, []rune(tt.pattern), false); ok {
+			t.Errorf("%q matches %q", tt.pattern, tt.text)
+		}
+	}
+}
+
+func TestSearchSmartCase(t *testing.T) {
+	items := []types.ClipboardItem{
+		{ID: 1, Content: "FooBar"},
+		{ID: 2, Content: "foobar"},
+		{ID: 3, Content: "FOOBAR"},
+	}
+	tests := []struct {
+		query string
+		mode  Mode
+		want  []int64
+	}{
+		{"foo", ModeFuzzy, []int64{1, 2, 3}},
+		{"Foo", ModeFuzzy, []int64{1}},
+		{"oB", ModeFuzzy, []int64{1}},
+		{"fB", ModeFuzzy, nil},
+		{"FB", ModeFuzzy, []int64{1, 3}},
+		{"bar", ModeExact, []int64{1, 2, 3}},
+		{"Bar", ModeExact, []int64{1}},
+		{"o+b", ModeRegex, []int64{1, 2, 3}},
+		{"O+B", ModeRegex, []int64{3}},
+	}
+
+	for _, tt := range tests {
+		results, err := Search(items, tt.query, tt.mode)
+		if err != nil {
+			t.Fatalf("Search(%q, %s): %v", tt.query, tt.mode, err)
+		}
+		var ids []int64
+		for _, r := range results {
+			ids = append(ids, r.Item.ID)
+		}
+		slices.Sort(ids)
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("Search(%q, %s) = %v, want %v", tt.query, tt.mode, ids, tt.want)
+		}
+	}
+}
+
+func TestSearchRanksBestFirst(t *testing.T) {
+	items := []types.ClipboardItem{
+		{ID: 1, Content: "a long line that has c, l and i in it somewhere"},
+		{ID: 2, Content: "run the cli"},
+		{ID: 3, Content: "cliptui"},
+	}
+	results, err := Search(items, "cli", ModeFuzzy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, r := range results {
+		ids = append(ids, r.Item.ID)
+	}
+	// Both whole matches start a word, the shorter item wins the tie
+	if want := []int64{3, 2, 1}; !slices.Equal(ids, want) {
+		t.Errorf("ranked %v, want %v", ids, want)
+	}
+}
+
+// benchItems generates n items of mixed content, a few of which mention
+// the benchmark queries
+func benchItems(n int) []types.ClipboardItem {
+	words := strings.Fields("the quick brown fox jumps over a lazy dog while " +
+		"copying config files into the clipboard history of cliptui")
+	items := make([]types.ClipboardItem, n)
+	for i := range items {
+		var b strings.Builder
+		for j := range 8 + i%24 {
+			if j > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(words[(i*7+j*3)%len(words)])
+		}
+		if i%100 == 0 {
+			fmt.Fprintf(&b, " https://example.com/items/%d", i)
+		}
+		items[i] = types.ClipboardItem{ID: int64(i + 1), Content: b.String()}
+	}
+	return items
+}
+
+func BenchmarkSearch(b *testing.B) {
+	items := benchItems(50000)
+	benchmarks := []struct {
+		mode  Mode
+		query string
+	}{
+		{ModeFuzzy, "cfgclip"},
+		{ModeExact, "clipboard history"},
+		{ModeRegex, `items/\d+0\b`},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(string(bm.mode), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := Search(items, bm.query, bm.mode); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,425 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+// Fields that filter a query, written as "type:url" or "len>200"
+const (
+	FieldType   = "type"
+	FieldTag    = "tag"
+	FieldPinned = "pinned"
+	FieldAfter  = "after"
+	FieldBefore = "before"
+	FieldLen    = "len"
+)
+
+// Fields lists every field a query can filter on
+var Fields = []string{FieldType, FieldTag, FieldPinned, FieldAfter, FieldBefore, FieldLen}
+
+// timeLayouts are the absolute time formats accepted by ParseTime
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// Node is an element of a parsed query
+type Node interface {
+	// Match reports whether item satisfies the node. Text is matched as a
+	// smart-case substring; Search matches it according to the search mode.
+	Match(item types.ClipboardItem) bool
+	String() string
+}
+
+// And matches items that match all of its nodes. It is the root of every
+// parsed query.
+type And []Node
+
+// Not matches items that its node does not match, written as "-term"
+type Not struct {
+	Node Node
+}
+
+// Text matches content containing a word, or a phrase when Quoted
+type Text struct {
+	Value  string
+	Quoted bool
+}
+
+// TypeFilter matches items of a type, written as "type:url"
+type TypeFilter struct {
+	Type string
+}
+
+// TagFilter matches items with a tag, written as "tag:work"
+type TagFilter struct {
+	Tag string
+}
+
+// PinnedFilter matches pinned or unpinned items, written as "pinned:true"
+type PinnedFilter struct {
+	Pinned bool
+}
+
+// TimeFilter matches items copied at or after Time, written as "after:2d",
+// or before it when Before is set, written as "before:2024-05-01"
+type TimeFilter struct {
+	Time   time.Time
+	Before bool
+	// Value is the time as written in the query
+	Value string
+}
+
+// LengthFilter compares the content length in bytes with Length, written as
+// "len>200". Op is one of =, >, <, >= and <=.
+type LengthFilter struct {
+	Op     string
+	Length int
+}
+
+// SyntaxError reports a query that cannot be parsed
+type SyntaxError struct {
+	Offset int // byte offset of the offending term
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s (column %d)", e.Msg, e.Offset+1)
+}
+
+// Match reports whether item matches every node
+func (n And) Match(item types.ClipboardItem) bool {
+	for _, node := range n {
+		if !node.Match(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n And) String() string {
+	parts := make([]string, len(n))
+	for i, node := range n {
+		parts[i] = node.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// Match reports whether item does not match the negated node
+func (n Not) Match(item types.ClipboardItem) bool {
+	return !n.Node.Match(item)
+}
+
+func (n Not) String() string {
+	return "-" + n.Node.String()
+}
+
+// CaseSensitive reports whether the text is matched with its case, which
+// it is when it contains an uppercase letter
+func (n Text) CaseSensitive() bool {
+	return hasUpper(n.Value)
+}
+
+// Match reports whether the content contains the text
+func (n Text) Match(item types.ClipboardItem) bool {
+	if n.CaseSensitive() {
+		return strings.Contains(item.Content, n.Value)
+	}
+	return strings.Contains(strings.ToLower(item.Content), strings.ToLower(n.Value))
+}
+
+func (n Text) String() string {
+	if n.Quoted {
+		return strconv.Quote(n.Value)
+	}
+	return n.Value
+}
+
+// Match reports whether the item has the type
+func (n TypeFilter) Match(item types.ClipboardItem) bool {
+	return item.Type == n.Type
+}
+
+func (n TypeFilter) String() string {
+	return FieldType + ":" + n.Type
+}
+
+// Match reports whether the item has the tag
+func (n TagFilter) Match(item types.ClipboardItem) bool {
+	for _, tag := range item.Tags {
+		if tag == n.Tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (n TagFilter) String() string {
+	return FieldTag + ":" + n.Tag
+}
+
+// Match reports whether the item is pinned as required
+func (n PinnedFilter) Match(item types.ClipboardItem) bool {
+	return item.Pinned == n.Pinned
+}
+
+func (n PinnedFilter) String() string {
+	return FieldPinned + ":" + strconv.FormatBool(n.Pinned)
+}
+
+// Match reports whether the item was copied on the right side of the time
+func (n TimeFilter) Match(item types.ClipboardItem) bool {
+	if n.Before {
+		return item.Timestamp.Before(n.Time)
+	}
+	return !item.Timestamp.Before(n.Time)
+}
+
+func (n TimeFilter) String() string {
+	value := n.Value
+	if strings.ContainsAny(value, " \t") {
+		value = strconv.Quote(value)
+	}
+	if n.Before {
+		return FieldBefore + ":" + value
+	}
+	return FieldAfter + ":" + value
+}
+
+// Match compares the content length
+func (n LengthFilter) Match(item types.ClipboardItem) bool {
+	length := len(item.Content)
+	switch n.Op {
+	case ">":
+		return length > n.Length
+	case "<":
+		return length < n.Length
+	case ">=":
+		return length >= n.Length
+	case "<=":
+		return length <= n.Length
+	}
+	return length == n.Length
+}
+
+func (n LengthFilter) String() string {
+	return FieldLen + n.Op + strconv.Itoa(n.Length)
+}
+
+// token is a single whitespace separated term of a query
+type token struct {
+	text   string
+	quoted bool
+	negate bool
+	offset int
+}
+
+// Parse parses a query such as `type:url after:2d tag:work -draft "exact
+// phrase" len>200`. Every term must match. Words and quoted phrases are
+// matched against the content, fields filter on item attributes and a
+// leading "-" negates a term.
+func Parse(query string) (And, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+
+	root := And{}
+	for _, tok := range tokens {
+		node, err := parseTerm(tok)
+		if err != nil {
+			return nil, &SyntaxError{Offset: tok.offset, Msg: err.Error()}
+		}
+		if tok.negate {
+			node = Not{Node: node}
+		}
+		root = append(root, node)
+	}
+	return root, nil
+}
+
+// tokenize splits a query into terms, keeping quoted phrases and quoted
+// field values, like after:"2024-05-01 12:00", together
+func tokenize(query string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(query); {
+		if unicode.IsSpace(rune(query[i])) {
+			i++
+			continue
+		}
+
+		tok := token{offset: i}
+		if query[i] == '-' && i+1 < len(query) && !unicode.IsSpace(rune(query[i+1])) {
+			tok.negate = true
+			i++
+		}
+
+		if query[i] == '"' {
+			text, end, ok := unquote(query, i)
+			if !ok {
+				return nil, &SyntaxError{Offset: tok.offset, Msg: "unterminated quote"}
+			}
+			tok.text, tok.quoted, i = text, true, end
+		} else {
+			start := i
+			for i < len(query) && !unicode.IsSpace(rune(query[i])) {
+				if query[i] == '"' && fieldPrefix(query[start:i]) {
+					break
+				}
+				i++
+			}
+			tok.text = query[start:i]
+			if i < len(query) && query[i] == '"' {
+				value, end, ok := unquote(query, i)
+				if !ok {
+					return nil, &SyntaxError{Offset: tok.offset, Msg: "unterminated quote"}
+				}
+				tok.text += value
+				i = end
+			}
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens, nil
+}
+
+// unquote reads the quoted string starting at query[start], where \" and
+// \\ are escapes. It returns the string, the offset after the closing
+// quote and whether there was one.
+func unquote(query string, start int) (string, int, bool) {
+	var b strings.Builder
+	for i := start + 1; i < len(query); i++ {
+		c := query[i]
+		if c == '\\' && i+1 < len(query) && (query[i+1] == '"' || query[i+1] == '\\') {
+			i++
+			b.WriteByte(query[i])
+			continue
+		}
+		if c == '"' {
+			return b.String(), i + 1, true
+		}
+		b.WriteByte(c)
+	}
+	return "", len(query), false
+}
+
+// fieldPrefix reports whether text is a field and its operator, so that a
+// quote after it starts the value
+func fieldPrefix(text string) bool {
+	i := strings.IndexAny(text, ":<>=")
+	if i <= 0 || !isField(strings.ToLower(text[:i])) {
+		return false
+	}
+	switch text[i:] {
+	case ":", "<", ">", "=", "<=", ">=":
+		return true
+	}
+	return false
+}
+
+// parseTerm turns a token into a field filter, or text if it names no field
+func parseTerm(tok token) (Node, error) {
+	if tok.quoted {
+		return Text{Value: tok.text, Quoted: true}, nil
+	}
+
+	i := strings.IndexAny(tok.text, ":<>=")
+	if i <= 0 {
+		return Text{Value: tok.text}, nil
+	}
+	field := strings.ToLower(tok.text[:i])
+	if !isField(field) {
+		// Not a field, like the "https:" of a URL
+		return Text{Value: tok.text}, nil
+	}
+
+	op, value := tok.text[i:i+1], tok.text[i+1:]
+	if strings.HasPrefix(value, "=") && (op == "<" || op == ">") {
+		op, value = op+"=", value[1:]
+	}
+	if field != FieldLen && op != ":" {
+		return nil, fmt.Errorf("%s only supports %s:", field, field)
+	}
+	if value == "" {
+		return nil, fmt.Errorf("missing value for %s%s", field, op)
+	}
+
+	switch field {
+	case FieldType:
+		if types.KnownType(value) {
+			return TypeFilter{Type: value}, nil
+		}
+		return nil, fmt.Errorf("unknown type %q, expected one of %s", value, strings.Join(types.AllTypes(), ", "))
+	case FieldTag:
+		if err := types.ValidateTag(value); err != nil {
+			return nil, err
+		}
+		return TagFilter{Tag: value}, nil
+	case FieldPinned:
+		pinned, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("pinned must be true or false, got %q", value)
+		}
+		return PinnedFilter{Pinned: pinned}, nil
+	case FieldAfter, FieldBefore:
+		t, err := ParseTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		return TimeFilter{Time: t, Before: field == FieldBefore, Value: value}, nil
+	case FieldLen:
+		length, err := strconv.Atoi(value)
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("len must be compared with a number of bytes, got %q", value)
+		}
+		if op == ":" {
+			op = "="
+		}
+		return LengthFilter{Op: op, Length: length}, nil
+	}
+	return nil, fmt.Errorf("unknown field %q", field)
+}
+
+// isField reports whether name is a query field
+func isField(name string) bool {
+	for _, f := range Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTime reads a point in time: a duration back from now, with "d"
+// accepted for days, or an absolute time in local time unless it has a zone
+func ParseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if d, err := time.ParseDuration(days + "h"); err == nil {
+			return time.Now().Add(-d * 24), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a duration nor a time like 2006-01-02T15:04 or \"2006-01-02 15:04\"", s)
+}
@@ -0,0 +1,206 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	may1 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		query string
+		want  And
+	}{
+		{"", And{}},
+		{"   ", And{}},
+		{"hello", And{Text{Value: "hello"}}},
+		{"hello  world", And{Text{Value: "hello"}, Text{Value: "world"}}},
+
+		// Fields
+		{"type:url", And{TypeFilter{Type: "url"}}},
+		{"TYPE:code", And{TypeFilter{Type: "code"}}},
+		{"tag:work", And{TagFilter{Tag: "work"}}},
+		{"pinned:true", And{PinnedFilter{Pinned: true}}},
+		{"pinned:false", And{PinnedFilter{Pinned: false}}},
+		{"pinned:1", And{PinnedFilter{Pinned: true}}},
+		{"after:2024-05-01", And{TimeFilter{Time: may1, Value: "2024-05-01"}}},
+		{"before:2024-05-01", And{TimeFilter{Time: may1, Before: true, Value: "2024-05-01"}}},
+		{"before:2024-05-01T12:00:00Z", And{TimeFilter{
+			Time:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			Before: true,
+			Value:  "2024-05-01T12:00:00Z",
+		}}},
+		{"after:2024-05-01T09:30", And{TimeFilter{Time: may1.Add(9*time.Hour + 30*time.Minute), Value: "2024-05-01T09:30"}}},
+		// A quoted value keeps its spaces
+		{`after:"2024-05-01 09:30"`, And{TimeFilter{Time: may1.Add(9*time.Hour + 30*time.Minute), Value: "2024-05-01 09:30"}}},
+		{`-before:"2024-05-01 09:30:15" x`, And{
+			Not{TimeFilter{Time: may1.Add(9*time.Hour + 30*time.Minute + 15*time.Second), Before: true, Value: "2024-05-01 09:30:15"}},
+			Text{Value: "x"},
+		}},
+		{`TAG:"work"`, And{TagFilter{Tag: "work"}}},
+		{`len>="5"`, And{LengthFilter{Op: ">=", Length: 5}}},
+
+		// Lengths
+		{"len>200", And{LengthFilter{Op: ">", Length: 200}}},
+		{"len<10", And{LengthFilter{Op: "<", Length: 10}}},
+		{"len>=5", And{LengthFilter{Op: ">=", Length: 5}}},
+		{"len<=5", And{LengthFilter{Op: "<=", Length: 5}}},
+		{"len:7", And{LengthFilter{Op: "=", Length: 7}}},
+		{"len=7", And{LengthFilter{Op: "=", Length: 7}}},
+		{"len>0", And{LengthFilter{Op: ">", Length: 0}}},
+
+		// Negation
+		{"-draft", And{Not{Text{Value: "draft"}}}},
+		{"-type:url", And{Not{TypeFilter{Type: "url"}}}},
+		{"-tag:work", And{Not{TagFilter{Tag: "work"}}}},
+		{"-len>=100", And{Not{LengthFilter{Op: ">=", Length: 100}}}},
+		{`-"exact phrase"`, And{Not{Text{Value: "exact phrase", Quoted: true}}}},
+		// A lone dash is a word
+		{"a - b", And{Text{Value: "a"}, Text{Value: "-"}, Text{Value: "b"}}},
+		{"--x", And{Not{Text{Value: "-x"}}}},
+
+		// Quotes
+		{`"exact phrase"`, And{Text{Value: "exact phrase", Quoted: true}}},
+		{`""`, And{Text{Value: "", Quoted: true}}},
+		{`"type:url"`, And{Text{Value: "type:url", Quoted: true}}},
+		{`"say \"hi\""`, And{Text{Value: `say "hi"`, Quoted: true}}},
+		{`"back\\slash"`, And{Text{Value: `back\slash`, Quoted: true}}},
+		// Other backslashes are kept
+		{`"a\nb"`, And{Text{Value: `a\nb`, Quoted: true}}},
+		{`"a"b`, And{Text{Value: "a", Quoted: true}, Text{Value: "b"}}},
+		{`say"hi"`, And{Text{Value: `say"hi"`}}},
+		// Only the value of a field is quoted, not that of other words
+		{`foo:"a b"`, And{Text{Value: `foo:"a`}, Text{Value: `b"`}}},
+
+		// Words that only look like fields stay text
+		{"https://example.com/a?b=c", And{Text{Value: "https://example.com/a?b=c"}}},
+		{"foo:bar", And{Text{Value: "foo:bar"}}},
+		{":type", And{Text{Value: ":type"}}},
+		{"a=b", And{Text{Value: "a=b"}}},
+		{"x>1", And{Text{Value: "x>1"}}},
+
+		{`type:code "fmt.Println" -len>1000 tag:go`, And{
+			TypeFilter{Type: "code"},
+			Text{Value: "fmt.Println", Quoted: true},
+			Not{LengthFilter{Op: ">", Length: 1000}},
+			TagFilter{Tag: "go"},
+		}},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseRelativeTime(t *testing.T) {
+	tests := []struct {
+		query string
+		ago   time.Duration
+	}{
+		{"after:2d", 48 * time.Hour},
+		{"after:1.5d", 36 * time.Hour},
+		{"before:90m", 90 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		root, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		filter, ok := root[0].(TimeFilter)
+		if !ok {
+			t.Errorf("Parse(%q) = %#v, want a TimeFilter", tt.query, root)
+			continue
+		}
+		if ago := time.Since(filter.Time); ago < tt.ago || ago > tt.ago+time.Minute {
+			t.Errorf("Parse(%q) is %s ago, want %s", tt.query, ago, tt.ago)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query  string
+		offset int
+		column string // the end of the message
+	}{
+		{`"open`, 0, "unterminated quote (column 1)"},
+		{`foo "bar`, 4, "unterminated quote (column 5)"},
+		{`x -"bar`, 2, "unterminated quote (column 3)"},
+		{`x after:"2024-05-01`, 2, "unterminated quote (column 3)"},
+		{`"escaped \"`, 0, "unterminated quote (column 1)"},
+		{"type:nope", 0, "(column 1)"},
+		{"a  -type:nope", 3, "(column 4)"},
+		{"type:", 0, "missing value for type: (column 1)"},
+		{"type>url", 0, "type only supports type: (column 1)"},
+		{"tag:", 0, "missing value for tag: (column 1)"},
+		{"a tag:a,b", 2, "(column 3)"},
+		{"pinned:maybe", 0, `pinned must be true or false, got "maybe" (column 1)`},
+		{"after:yesterday", 0, "(column 1)"},
+		{"before:2024-13-01", 0, "(column 1)"},
+		{"len>x", 0, `got "x" (column 1)`},
+		{"len:-1", 0, `got "-1" (column 1)`},
+		{"len>", 0, "missing value for len> (column 1)"},
+		{"é len>=", 3, "missing value for len>= (column 4)"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q) error = %v, want a SyntaxError", tt.query, err)
+			continue
+		}
+		if syntaxErr.Offset != tt.offset {
+			t.Errorf("Parse(%q) error offset = %d, want %d", tt.query, syntaxErr.Offset, tt.offset)
+		}
+		if !strings.HasSuffix(err.Error(), tt.column) {
+			t.Errorf("Parse(%q) error = %q, want it to end in %q", tt.query, err, tt.column)
+		}
+	}
+}
+
+func TestParseUnknownType(t *testing.T) {
+	_, err := Parse("type:nope")
+	if err == nil {
+		t.Fatal("Parse(type:nope) succeeded")
+	}
+	want := `unknown type "nope", expected one of `
+	if !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("error = %q, want it to start with %q", err, want)
+	}
+}
+
+func TestParseStringRoundTrip(t *testing.T) {
+	queries := []string{
+		`type:url tag:work -draft "exact phrase" len>=200`,
+		`-"say \"hi\"" pinned:false before:2024-05-01`,
+		`https://example.com len=3`,
+		`after:"2024-05-01 09:30" -before:2024-06-01T10:00`,
+	}
+	for _, query := range queries {
+		root, err := Parse(query)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", query, err)
+		}
+		again, err := Parse(root.String())
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", root.String(), err)
+		}
+		if !reflect.DeepEqual(again, root) {
+			t.Errorf("Parse(%q) = %#v, want %#v", root.String(), again, root)
+		}
+	}
+}
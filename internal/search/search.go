@@ -1,30 +1,212 @@
 package search
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/dvd/cliptui/pkg/types"
 )
 
-// Filter performs case-insensitive substring search on clipboard items
-func Filter(items []types.ClipboardItem, query string) []types.ClipboardItem {
-	if query == "" {
-		return items
+// Mode selects how a query is matched against item content
+type Mode string
+
+// Search modes
+const (
+	ModeFuzzy Mode = "fuzzy"
+	ModeExact Mode = "exact"
+	ModeRegex Mode = "regex"
+)
+
+// Modes lists every search mode, in the order they are cycled through
+var Modes = []Mode{ModeFuzzy, ModeExact, ModeRegex}
+
+// ParseMode returns the mode called name
+func ParseMode(name string) (Mode, error) {
+	for _, m := range Modes {
+		if string(m) == name {
+			return m, nil
+		}
 	}
+	return "", fmt.Errorf("unknown search mode %q, expected fuzzy, exact or regex", name)
+}
 
-	if len(items) == 0 {
-		return items
+// Next returns the mode that follows m when cycling through modes
+func (m Mode) Next() Mode {
+	for i, mode := range Modes {
+		if mode == m {
+			return Modes[(i+1)%len(Modes)]
+		}
 	}
+	return ModeFuzzy
+}
 
-	query = strings.ToLower(query)
-	// Pre-allocate slice with capacity to avoid reallocations
-	// In worst case, all items match, so use len(items) as capacity
-	results := make([]types.ClipboardItem, 0, len(items))
+// Result is an item that matched a query
+type Result struct {
+	Item  types.ClipboardItem
+	Score int
+	// Positions are the byte offsets in Item.Content of the matched
+	// characters, in ascending order
+	Positions []int
+}
+
+// matcher matches a text term against content, returning a score and the
+// matched byte offsets
+type matcher func(content string) (int, []int, bool)
 
+// Search returns the items matching a query in the syntax of Parse. Text
+// terms are matched according to mode; in fuzzy mode results are ranked best
+// first, shorter items winning ties, otherwise they keep the order of items.
+// Text is smart-case: it ignores case unless it contains an uppercase letter.
+func Search(items []types.ClipboardItem, query string, mode Mode) ([]Result, error) {
+	root, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Text terms are matched in the search mode and highlighted, everything
+	// else only filters
+	type textTerm struct {
+		match  matcher
+		negate bool
+	}
+	var texts []textTerm
+	var filters And
+	ranked := false
+	for _, node := range root {
+		negate := false
+		inner := node
+		if not, ok := node.(Not); ok {
+			negate, inner = true, not.Node
+		}
+		text, ok := inner.(Text)
+		if !ok {
+			filters = append(filters, node)
+			continue
+		}
+		if negate {
+			// Like fzf, exclude exact matches only; a fuzzy pattern would
+			// exclude far more than intended
+			text.Quoted = true
+		}
+		m, err := newMatcher(text, mode)
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, textTerm{match: m, negate: negate})
+		ranked = ranked || (mode == ModeFuzzy && !text.Quoted && !negate)
+	}
+
+	results := make([]Result, 0, len(items))
 	for _, item := range items {
-		if strings.Contains(strings.ToLower(item.Content), query) {
-			results = append(results, item)
+		if !filters.Match(item) {
+			continue
+		}
+		result := Result{Item: item}
+		matched := true
+		for _, term := range texts {
+			score, positions, ok := term.match(item.Content)
+			if ok == term.negate {
+				matched = false
+				break
+			}
+			if !term.negate {
+				result.Score += score
+				result.Positions = append(result.Positions, positions...)
+			}
+		}
+		if !matched {
+			continue
+		}
+		if len(texts) > 1 {
+			sort.Ints(result.Positions)
+			result.Positions = dedupe(result.Positions)
 		}
+		results = append(results, result)
 	}
 
-	return results
+	if ranked {
+		sort.SliceStable(results, func(i, j int) bool {
+			if results[i].Score != results[j].Score {
+				return results[i].Score > results[j].Score
+			}
+			return len(results[i].Item.Content) < len(results[j].Item.Content)
+		})
+	}
+	return results, nil
+}
+
+// newMatcher builds the matcher of a text term. Quoted phrases are matched
+// exactly in fuzzy mode; in the other modes quotes only group words.
+func newMatcher(text Text, mode Mode) (matcher, error) {
+	caseSensitive := hasUpper(text.Value)
+
+	if mode == ModeFuzzy && !text.Quoted {
+		value := text.Value
+		if !caseSensitive {
+			value = strings.ToLower(value)
+		}
+		pattern := []rune(value)
+		return func(content string) (int, []int, bool) {
+			return fuzzyMatch(content, pattern, caseSensitive)
+		}, nil
+	}
+
+	expr := text.Value
+	if mode != ModeRegex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if !caseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return func(content string) (int, []int, bool) {
+		loc := re.FindStringIndex(content)
+		// An empty match would match every item
+		if loc == nil || loc[0] == loc[1] {
+			return 0, nil, false
+		}
+		positions := make([]int, 0, loc[1]-loc[0])
+		for i := range content[loc[0]:loc[1]] {
+			positions = append(positions, loc[0]+i)
+		}
+		return 0, positions, true
+	}, nil
+}
+
+// hasUpper reports whether s contains an uppercase letter
+func hasUpper(s string) bool {
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupe removes repeated values from a sorted slice
+func dedupe(s []int) []int {
+	out := s[:0]
+	for i, v := range s {
+		if i == 0 || v != s[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// runeBefore returns the rune that ends just before byte offset i, or a
+// space at the start of s
+func runeBefore(s string, i int) rune {
+	if i == 0 {
+		return ' '
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return r
 }
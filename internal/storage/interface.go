@@ -8,12 +8,40 @@ type Store interface {
 	// Add inserts a new clipboard item
 	Add(content string) error
 
+	// AddItem inserts an item as given, filling in its type, preview and
+	// timestamp when they are empty, and returns it with its ID
+	AddItem(item types.ClipboardItem) (*types.ClipboardItem, error)
+
 	// GetAll retrieves all clipboard items, newest first
 	GetAll() ([]types.ClipboardItem, error)
 
 	// GetRecent retrieves the N most recent items
 	GetRecent(limit int) ([]types.ClipboardItem, error)
 
+	// List returns the items matching q, newest first
+	List(q types.Query) ([]types.ClipboardItem, error)
+
+	// Get returns the item with the given ID, or nil if it does not exist
+	Get(id int64) (*types.ClipboardItem, error)
+
+	// Search returns up to limit items whose content contains query
+	Search(query string, limit int) ([]types.ClipboardItem, error)
+
+	// SetPinned pins or unpins an item
+	SetPinned(id int64, pinned bool) error
+
+	// SetType changes the type of an item
+	SetType(id int64, itemType string) error
+
+	// SetLanguage sets the programming language of an item
+	SetLanguage(id int64, language string) error
+
+	// SetTags replaces the tags of an item
+	SetTags(id int64, tags []string) error
+
+	// Touch moves an item to the top of the history by making it the newest
+	Touch(id int64) error
+
 	// Delete removes an item by ID
 	Delete(id int64) error
 
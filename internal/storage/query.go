@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/dvd/cliptui/internal/search"
+	"github.com/mattn/go-sqlite3"
+)
+
+// driverName is the SQLite driver that adds the functions compiled searches use
+const driverName = "sqlite3_cliptui"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			// SQLite's lower() and LIKE only fold ASCII letters
+			return conn.RegisterFunc("fold", strings.ToLower, true)
+		},
+	})
+}
+
+// compileSearch translates a search query into a SQL condition and its
+// arguments. Every condition selects the same items as the Match method of
+// its node: text is matched as a substring, ignoring case unless it contains
+// an uppercase letter, and tags are compared exactly.
+func compileSearch(query string) (string, []any, error) {
+	root, err := search.Parse(query)
+	if err != nil {
+		return "", nil, err
+	}
+	return compileNode(root)
+}
+
+// compileNode translates a single node of a parsed query
+func compileNode(node search.Node) (string, []any, error) {
+	switch n := node.(type) {
+	case search.And:
+		if len(n) == 0 {
+			return "1", nil, nil
+		}
+		var where []string
+		var args []any
+		for _, child := range n {
+			cond, childArgs, err := compileNode(child)
+			if err != nil {
+				return "", nil, err
+			}
+			where = append(where, cond)
+			args = append(args, childArgs...)
+		}
+		return "(" + strings.Join(where, " AND ") + ")", args, nil
+	case search.Not:
+		cond, args, err := compileNode(n.Node)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT " + cond, args, nil
+	case search.Text:
+		if n.CaseSensitive() {
+			return "(instr(content, ?) > 0)", []any{n.Value}, nil
+		}
+		return "(instr(fold(content), ?) > 0)", []any{strings.ToLower(n.Value)}, nil
+	case search.TypeFilter:
+		return "(type = ?)", []any{n.Type}, nil
+	case search.TagFilter:
+		return "(instr(',' || tags || ',', ?) > 0)", []any{"," + n.Tag + ","}, nil
+	case search.PinnedFilter:
+		return "(pinned = ?)", []any{n.Pinned}, nil
+	case search.TimeFilter:
+		if n.Before {
+			return "(timestamp < ?)", []any{n.Time}, nil
+		}
+		return "(timestamp >= ?)", []any{n.Time}, nil
+	case search.LengthFilter:
+		return "(length(CAST(content AS BLOB)) " + n.Op + " ?)", []any{n.Length}, nil
+	}
+	return "", nil, fmt.Errorf("unsupported query term %s", node)
+}
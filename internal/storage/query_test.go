@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dvd/cliptui/internal/search"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+// queryItems are stored once and searched with every query of
+// TestCompileSearchMatchesNodes
+var queryItems = []types.ClipboardItem{
+	{Content: "https://example.com/search?q=clip", Type: types.TypeURL, Tags: []string{"work"}, Pinned: true},
+	{Content: "func main() {\n\tfmt.Println(\"hi\")\n}", Type: types.TypeCode, Tags: []string{"go", "work-notes"}},
+	{Content: "Hello World", Type: types.TypeText},
+	{Content: "hello world, again", Type: types.TypeText, Tags: []string{"Work"}},
+	{Content: "100% sure_thing", Type: types.TypeText},
+	{Content: `say "hi" \ back`, Type: types.TypeText, Pinned: true},
+	{Content: "Crème brûlée CAFÉ", Type: types.TypeText},
+	{Content: "crème brûlée café", Type: types.TypeText},
+	{Content: "日本語のテキスト", Type: types.TypeText, Tags: []string{"日本"}},
+	{Content: "", Type: types.TypeText},
+	{Content: "draft: not done", Type: types.TypeMarkdown, Tags: []string{"draft"}},
+}
+
+func TestCompileSearchMatchesNodes(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	var items []types.ClipboardItem
+	for i, item := range queryItems {
+		item.Timestamp = base.Add(time.Duration(i) * 24 * time.Hour)
+		added, err := s.AddItem(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, *added)
+	}
+
+	queries := []string{
+		"",
+		"hello",
+		"Hello",
+		"HELLO",
+		"world -again",
+		`"hello world"`,
+		`-"hello world"`,
+		`"say \"hi\""`,
+		`\`,
+		"100%",
+		"%",
+		"_",
+		"sure_thing",
+		"crème",
+		"Crème",
+		"café",
+		"CAFÉ",
+		"テキスト",
+		"type:url",
+		"-type:text",
+		"type:code fmt",
+		"tag:work",
+		"-tag:work",
+		"tag:Work",
+		"tag:work-notes",
+		"tag:日本",
+		"pinned:true",
+		"pinned:false hello",
+		"after:2024-05-03",
+		"before:2024-05-03",
+		"after:2024-05-02T12:00:00Z before:2024-05-06",
+		"len>10",
+		"len<10",
+		"len>=11",
+		"len<=11",
+		"len:0",
+		"len=24",
+		"-len>20 -pinned:true",
+		"https://example.com",
+	}
+
+	for _, query := range queries {
+		root, err := search.Parse(query)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", query, err)
+		}
+		var want []int64
+		for _, item := range items {
+			if root.Match(item) {
+				want = append(want, item.ID)
+			}
+		}
+
+		found, err := s.List(types.Query{Search: query})
+		if err != nil {
+			t.Errorf("List(%q): %v", query, err)
+			continue
+		}
+		var got []int64
+		for _, item := range found {
+			got = append(got, item.ID)
+		}
+		slices.Sort(got)
+
+		if !slices.Equal(got, want) {
+			t.Errorf("SQL for %q finds %v, Match finds %v", query, got, want)
+		}
+	}
+}
+
+func TestCompileSearchErrors(t *testing.T) {
+	for _, query := range []string{`"open`, "type:nope", "len>x"} {
+		if _, _, err := compileSearch(query); err == nil {
+			t.Errorf("compileSearch(%q) succeeded", query)
+		}
+	}
+}
@@ -2,21 +2,28 @@ package storage
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/dvd/cliptui/internal/language"
 	"github.com/dvd/cliptui/pkg/types"
 )
 
+// itemColumns lists the columns scanned by scanItem, in order
+const itemColumns = "id, content, type, preview, timestamp, pinned, tags, expires_at, language"
+
 // Storage handles clipboard history persistence
 type Storage struct {
-	db *sql.DB
+	db       *sql.DB
+	path     string
+	observer func(types.Event)
 }
 
 // New creates a new storage instance
 func New(dbPath string) (*Storage, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open(driverName, dbPath)
 	if err != nil {
 		return nil, err
 	}
@@ -27,7 +34,8 @@ func New(dbPath string) (*Storage, error) {
 			content TEXT NOT NULL,
 			type TEXT NOT NULL,
 			preview TEXT NOT NULL,
-			timestamp DATETIME NOT NULL
+			timestamp DATETIME NOT NULL,
+			pinned INTEGER NOT NULL DEFAULT 0
 		);
 		CREATE INDEX IF NOT EXISTS idx_timestamp ON clipboard_history(timestamp DESC);
 	`)
@@ -35,39 +43,111 @@ func New(dbPath string) (*Storage, error) {
 		return nil, err
 	}
 
+	s := &Storage{db: db, path: dbPath}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+
 	// Set secure file permissions (0600 = read/write for owner only)
 	if err := os.Chmod(dbPath, 0600); err != nil {
 		// Don't fail if we can't set permissions, just continue
 		// This allows the app to work on systems where chmod might not work
 	}
 
-	return &Storage{db: db}, nil
+	return s, nil
 }
 
-// Add inserts a new clipboard item
-func (s *Storage) Add(content string) error {
-	latest, err := s.GetLatest()
-	if err == nil && latest != nil && latest.Content == content {
-		return nil
+// migrate adds columns introduced after the initial schema to older databases
+func (s *Storage) migrate() error {
+	columns := []struct{ name, definition string }{
+		{"pinned", "INTEGER NOT NULL DEFAULT 0"},
+		{"tags", "TEXT NOT NULL DEFAULT ''"},
+		{"expires_at", "DATETIME"},
+		{"language", "TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, c := range columns {
+		if err := s.ensureColumn(c.name, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureColumn adds a column to clipboard_history if it does not exist yet
+func (s *Storage) ensureColumn(name, definition string) error {
+	rows, err := s.db.Query("PRAGMA table_info(clipboard_history)")
+	if err != nil {
+		return err
 	}
+	defer rows.Close()
 
-	itemType := types.DetectType(content)
-	preview := types.TruncatePreview(content, 100)
+	for rows.Next() {
+		var (
+			cid       int
+			colName   string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &colName, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if colName == name {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
 
-	_, err = s.db.Exec(
-		"INSERT INTO clipboard_history (content, type, preview, timestamp) VALUES (?, ?, ?, ?)",
-		content, itemType, preview, time.Now(),
-	)
+	_, err = s.db.Exec("ALTER TABLE clipboard_history ADD COLUMN " + name + " " + definition)
 	return err
 }
 
-// GetAll retrieves all clipboard items, newest first
-func (s *Storage) GetAll() ([]types.ClipboardItem, error) {
-	rows, err := s.db.Query(`
-		SELECT id, content, type, preview, timestamp
-		FROM clipboard_history
-		ORDER BY timestamp DESC
-	`)
+// Path returns the location of the database file
+func (s *Storage) Path() string {
+	return s.path
+}
+
+// SetObserver registers a function that is called after every change to the history
+func (s *Storage) SetObserver(fn func(types.Event)) {
+	s.observer = fn
+}
+
+// notify reports a change to the registered observer, if any
+func (s *Storage) notify(event types.Event) {
+	if s.observer != nil {
+		s.observer(event)
+	}
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanItem reads a single row selected with itemColumns
+func scanItem(row scanner) (*types.ClipboardItem, error) {
+	var item types.ClipboardItem
+	var tags string
+	var expiresAt sql.NullTime
+	err := row.Scan(&item.ID, &item.Content, &item.Type, &item.Preview, &item.Timestamp, &item.Pinned, &tags, &expiresAt, &item.Language)
+	if err != nil {
+		return nil, err
+	}
+	if tags != "" {
+		item.Tags = strings.Split(tags, ",")
+	}
+	if expiresAt.Valid {
+		item.ExpiresAt = expiresAt.Time
+	}
+	return &item, nil
+}
+
+// queryItems runs a query selecting itemColumns and collects the results
+func (s *Storage) queryItems(query string, args ...any) ([]types.ClipboardItem, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -75,64 +155,341 @@ func (s *Storage) GetAll() ([]types.ClipboardItem, error) {
 
 	var items []types.ClipboardItem
 	for rows.Next() {
-		var item types.ClipboardItem
-		err := rows.Scan(&item.ID, &item.Content, &item.Type, &item.Preview, &item.Timestamp)
+		item, err := scanItem(rows)
 		if err != nil {
 			return nil, err
 		}
-		items = append(items, item)
+		items = append(items, *item)
 	}
 
-	return items, nil
+	return items, rows.Err()
+}
+
+// Add inserts a new clipboard item, unless it repeats the latest one
+func (s *Storage) Add(content string) error {
+	latest, err := s.GetLatest()
+	if err == nil && latest != nil && latest.Content == content {
+		return nil
+	}
+
+	_, err = s.AddItem(types.ClipboardItem{Content: content})
+	return err
+}
+
+// AddItem inserts an item as given. The type is detected and the preview
+// built when they are empty, and the timestamp defaults to now.
+func (s *Storage) AddItem(item types.ClipboardItem) (*types.ClipboardItem, error) {
+	if item.Type == "" {
+		item.Type = types.DetectType(item.Content)
+	}
+	if item.Preview == "" {
+		item.Preview = types.MakePreview(item.Content, item.Type)
+	}
+	if item.Type == types.TypeCode && item.Language == "" {
+		item.Language = language.Detect(item.Content)
+	}
+	if item.Timestamp.IsZero() {
+		item.Timestamp = time.Now()
+	}
+	for _, tag := range item.Tags {
+		if err := types.ValidateTag(tag); err != nil {
+			return nil, err
+		}
+	}
+
+	var expiresAt sql.NullTime
+	if !item.ExpiresAt.IsZero() {
+		expiresAt = sql.NullTime{Time: item.ExpiresAt, Valid: true}
+	}
+
+	result, err := s.db.Exec(`
+		INSERT INTO clipboard_history (content, type, preview, timestamp, pinned, tags, expires_at, language)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		item.Content, item.Type, item.Preview, item.Timestamp, item.Pinned,
+		strings.Join(item.Tags, ","), expiresAt, item.Language,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+	s.notify(types.Event{Kind: types.EventAdded, ID: item.ID, Item: &item})
+	return &item, nil
+}
+
+// GetAll retrieves all clipboard items, newest first
+func (s *Storage) GetAll() ([]types.ClipboardItem, error) {
+	return s.List(types.Query{})
 }
 
 // GetRecent retrieves the N most recent items
 func (s *Storage) GetRecent(limit int) ([]types.ClipboardItem, error) {
-	rows, err := s.db.Query(`
-		SELECT id, content, type, preview, timestamp
+	return s.List(types.Query{Limit: limit})
+}
+
+// List returns the items matching q, newest first
+func (s *Storage) List(q types.Query) ([]types.ClipboardItem, error) {
+	where := []string{"(expires_at IS NULL OR expires_at > ?)"}
+	args := []any{time.Now()}
+	if q.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, q.Type)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "timestamp >= ?")
+		args = append(args, q.Since)
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "timestamp < ?")
+		args = append(args, q.Until)
+	}
+	if q.Pinned {
+		where = append(where, "pinned = 1")
+	}
+	if q.Tag != "" {
+		where = append(where, "(',' || tags || ',') LIKE ? ESCAPE '\\'")
+		args = append(args, "%,"+escapeLike(q.Tag)+",%")
+	}
+	if q.Search != "" {
+		cond, searchArgs, err := compileSearch(q.Search)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, cond)
+		args = append(args, searchArgs...)
+	}
+
+	query := "SELECT " + itemColumns + " FROM clipboard_history WHERE " + strings.Join(where, " AND ")
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative limit as no limit
+	}
+	query += " ORDER BY timestamp DESC LIMIT ?"
+	args = append(args, limit)
+
+	return s.queryItems(query, args...)
+}
+
+// Get returns the item with the given ID, or nil if it does not exist or
+// has expired
+func (s *Storage) Get(id int64) (*types.ClipboardItem, error) {
+	item, err := scanItem(s.db.QueryRow(`
+		SELECT `+itemColumns+`
+		FROM clipboard_history
+		WHERE id = ? AND (expires_at IS NULL OR expires_at > ?)
+	`, id, time.Now()))
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return item, err
+}
+
+// Search returns up to limit items whose content contains query, newest first
+func (s *Storage) Search(query string, limit int) ([]types.ClipboardItem, error) {
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative limit as no limit
+	}
+
+	pattern := "%" + escapeLike(query) + "%"
+	return s.queryItems(`
+		SELECT `+itemColumns+`
 		FROM clipboard_history
+		WHERE content LIKE ? ESCAPE '\'
+			AND (expires_at IS NULL OR expires_at > ?)
 		ORDER BY timestamp DESC
 		LIMIT ?
-	`, limit)
+	`, pattern, time.Now(), limit)
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SetPinned pins or unpins an item
+func (s *Storage) SetPinned(id int64, pinned bool) error {
+	_, err := s.db.Exec("UPDATE clipboard_history SET pinned = ? WHERE id = ?", pinned, id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
 
-	var items []types.ClipboardItem
+	if item, err := s.Get(id); err == nil && item != nil {
+		s.notify(types.Event{Kind: types.EventUpdated, ID: id, Item: item})
+	}
+	return nil
+}
+
+// SetType changes the type of an item and rebuilds its preview. Items that
+// become code get their language detected; other types have none.
+func (s *Storage) SetType(id int64, itemType string) error {
+	item, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if item == nil {
+		return fmt.Errorf("no item with ID %d", id)
+	}
+
+	if itemType == types.TypeCode && item.Type != types.TypeCode {
+		item.Language = language.Detect(item.Content)
+	} else if itemType != types.TypeCode {
+		item.Language = ""
+	}
+	item.Type = itemType
+	item.Preview = types.MakePreview(item.Content, itemType)
+	_, err = s.db.Exec("UPDATE clipboard_history SET type = ?, preview = ?, language = ? WHERE id = ?",
+		item.Type, item.Preview, item.Language, id)
+	if err != nil {
+		return err
+	}
+
+	s.notify(types.Event{Kind: types.EventUpdated, ID: id, Item: item})
+	return nil
+}
+
+// SetLanguage sets the programming language of an item, used for syntax
+// highlighting
+func (s *Storage) SetLanguage(id int64, lang string) error {
+	_, err := s.db.Exec("UPDATE clipboard_history SET language = ? WHERE id = ?", lang, id)
+	if err != nil {
+		return err
+	}
+
+	if item, err := s.Get(id); err == nil && item != nil {
+		s.notify(types.Event{Kind: types.EventUpdated, ID: id, Item: item})
+	}
+	return nil
+}
+
+// SetTags replaces the tags of an item
+func (s *Storage) SetTags(id int64, tags []string) error {
+	for _, tag := range tags {
+		if err := types.ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.db.Exec("UPDATE clipboard_history SET tags = ? WHERE id = ?", strings.Join(tags, ","), id)
+	if err != nil {
+		return err
+	}
+
+	if item, err := s.Get(id); err == nil && item != nil {
+		s.notify(types.Event{Kind: types.EventUpdated, ID: id, Item: item})
+	}
+	return nil
+}
+
+// Touch moves an item to the top of the history by setting its timestamp
+// to now
+func (s *Storage) Touch(id int64) error {
+	result, err := s.db.Exec("UPDATE clipboard_history SET timestamp = ? WHERE id = ?", time.Now(), id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no item with ID %d", id)
+	}
+
+	if item, err := s.Get(id); err == nil && item != nil {
+		s.notify(types.Event{Kind: types.EventUpdated, ID: id, Item: item})
+	}
+	return nil
+}
+
+// Prune deletes unpinned items beyond the newest maxItems or older than
+// maxAge; a zero limit is not enforced
+func (s *Storage) Prune(maxItems int, maxAge time.Duration) error {
+	var conditions []string
+	var args []any
+
+	if maxItems > 0 {
+		conditions = append(conditions, `id NOT IN (
+			SELECT id FROM clipboard_history ORDER BY timestamp DESC LIMIT ?
+		)`)
+		args = append(args, maxItems)
+	}
+	if maxAge > 0 {
+		conditions = append(conditions, "timestamp < ?")
+		args = append(args, time.Now().Add(-maxAge))
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	return s.deleteWhere("pinned = 0 AND ("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+// DeleteExpired removes items whose expiry time has passed, pinned or not
+func (s *Storage) DeleteExpired() error {
+	return s.deleteWhere("expires_at IS NOT NULL AND expires_at <= ?", time.Now())
+}
+
+// deleteWhere deletes the items matching a WHERE clause one by one, so that
+// the observer hears about each of them
+func (s *Storage) deleteWhere(where string, args ...any) error {
+	rows, err := s.db.Query("SELECT id FROM clipboard_history WHERE "+where, args...)
+	if err != nil {
+		return err
+	}
+
+	var ids []int64
 	for rows.Next() {
-		var item types.ClipboardItem
-		err := rows.Scan(&item.ID, &item.Content, &item.Type, &item.Preview, &item.Timestamp)
-		if err != nil {
-			return nil, err
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
 		}
-		items = append(items, item)
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	return items, nil
+	for _, id := range ids {
+		if err := s.Delete(id); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Delete removes an item by ID
 func (s *Storage) Delete(id int64) error {
 	_, err := s.db.Exec("DELETE FROM clipboard_history WHERE id = ?", id)
-	return err
+	if err != nil {
+		return err
+	}
+
+	s.notify(types.Event{Kind: types.EventDeleted, ID: id})
+	return nil
 }
 
 // Clear removes all items
 func (s *Storage) Clear() error {
 	_, err := s.db.Exec("DELETE FROM clipboard_history")
-	return err
+	if err != nil {
+		return err
+	}
+
+	s.notify(types.Event{Kind: types.EventCleared})
+	return nil
 }
 
-// GetLatest returns the most recent item
+// GetLatest returns the most recent item that has not expired
 func (s *Storage) GetLatest() (*types.ClipboardItem, error) {
-	var item types.ClipboardItem
-	err := s.db.QueryRow(`
-		SELECT id, content, type, preview, timestamp
+	item, err := scanItem(s.db.QueryRow(`
+		SELECT `+itemColumns+`
 		FROM clipboard_history
+		WHERE expires_at IS NULL OR expires_at > ?
 		ORDER BY timestamp DESC
 		LIMIT 1
-	`).Scan(&item.ID, &item.Content, &item.Type, &item.Preview, &item.Timestamp)
+	`, time.Now()))
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -141,7 +498,7 @@ func (s *Storage) GetLatest() (*types.ClipboardItem, error) {
 		return nil, err
 	}
 
-	return &item, nil
+	return item, nil
 }
 
 // Close closes the database connection
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+func TestExpiredItemsAreHidden(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	now := time.Now()
+	kept, err := s.AddItem(types.ClipboardItem{Content: "kept", Type: types.TypeText, Timestamp: now.Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := s.AddItem(types.ClipboardItem{
+		Content:   "one-time code",
+		Type:      types.TypeText,
+		Timestamp: now,
+		ExpiresAt: now.Add(-time.Second),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if item, err := s.Get(expired.ID); err != nil || item != nil {
+		t.Errorf("Get(expired) = %v, %v, want nothing", item, err)
+	}
+	if item, err := s.Get(kept.ID); err != nil || item == nil {
+		t.Errorf("Get(kept) = %v, %v, want the item", item, err)
+	}
+	if item, err := s.GetLatest(); err != nil || item == nil || item.ID != kept.ID {
+		t.Errorf("GetLatest() = %v, %v, want the kept item", item, err)
+	}
+	if items, err := s.List(types.Query{}); err != nil || len(items) != 1 {
+		t.Errorf("List() = %v, %v, want the kept item", items, err)
+	}
+
+	// The expired item is still stored until it is deleted
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM clipboard_history").Scan(&count); err != nil || count != 2 {
+		t.Fatalf("%d items stored, want 2 (%v)", count, err)
+	}
+	if err := s.DeleteExpired(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM clipboard_history").Scan(&count); err != nil || count != 1 {
+		t.Errorf("%d items stored after DeleteExpired, want 1 (%v)", count, err)
+	}
+}
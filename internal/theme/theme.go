@@ -0,0 +1,261 @@
+package theme
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gdamore/tcell/v2"
+)
+
+// NoColor is the theme used when the NO_COLOR environment variable is set
+const NoColor = "no-color"
+
+// Color is a terminal color written as a name ("yellow"), a hex value
+// ("#ffaa00") or "default" for the terminal's own color
+type Color struct {
+	tcell.Color
+}
+
+// UnmarshalText parses a color from a theme file
+func (c *Color) UnmarshalText(text []byte) error {
+	name := strings.ToLower(strings.TrimSpace(string(text)))
+	if name == "" || name == "default" {
+		c.Color = tcell.ColorDefault
+		return nil
+	}
+	color := tcell.GetColor(name)
+	if color == tcell.ColorDefault {
+		return fmt.Errorf("unknown color %q", text)
+	}
+	c.Color = color
+	return nil
+}
+
+// Tag returns the color for use in tview color tags like "[yellow]"
+func (c Color) Tag() string {
+	if c.Color == tcell.ColorDefault {
+		return "-"
+	}
+	return c.String()
+}
+
+// Theme holds the colors of every part of the TUI
+type Theme struct {
+	// Inherit names the theme whose colors are used for anything a theme file
+	// leaves out, "dark" by default
+	Inherit string `toml:"inherit"`
+
+	Text   Color `toml:"text"`
+	Muted  Color `toml:"muted"` // dates and empty list messages
+	Header Color `toml:"header"`
+	Number Color `toml:"number"`
+	Pinned Color `toml:"pinned"`
+	Paused Color `toml:"paused"`
+	Match  Color `toml:"match"`  // characters matched by a search, also bold
+	Marked Color `toml:"marked"` // the marker of items selected for a bulk action
+	Error  Color `toml:"error"`
+
+	// The selected row is drawn in reverse video when both are default
+	SelectionText       Color `toml:"selection_text"`
+	SelectionBackground Color `toml:"selection_background"`
+
+	ListBorder    Color `toml:"list_border"`
+	HelpBorder    Color `toml:"help_border"`
+	HelpText      Color `toml:"help_text"`
+	SearchBorder  Color `toml:"search_border"`
+	SearchText    Color `toml:"search_text"`
+	Placeholder   Color `toml:"placeholder"`
+	PreviewBorder Color `toml:"preview_border"`
+
+	// ChromaStyle is the syntax highlighting style of the preview; empty
+	// disables highlighting
+	ChromaStyle string `toml:"chroma_style"`
+
+	// Bold enables bold headers, numbers and titles
+	Bold bool `toml:"bold"`
+
+	// Monochrome is set for the no-color theme and the themes inheriting from
+	// it, which draw nothing in a color of its own, not even color swatches
+	Monochrome bool `toml:"-"`
+}
+
+// named returns a Color for a tcell color
+func named(c tcell.Color) Color {
+	return Color{c}
+}
+
+// builtin holds the themes that ship with ClipTUI
+var builtin = map[string]Theme{
+	"dark": {
+		Text:          named(tcell.ColorDefault),
+		Muted:         named(tcell.ColorDefault),
+		Header:        named(tcell.ColorYellow),
+		Number:        named(tcell.ColorYellow),
+		Pinned:        named(tcell.ColorYellow),
+		Paused:        named(tcell.ColorRed),
+		Match:         named(tcell.ColorAqua),
+		Marked:        named(tcell.ColorFuchsia),
+		Error:         named(tcell.ColorRed),
+		ListBorder:    named(tcell.ColorGreen),
+		HelpBorder:    named(tcell.ColorBlue),
+		HelpText:      named(tcell.ColorDefault),
+		SearchBorder:  named(tcell.ColorYellow),
+		SearchText:    named(tcell.ColorWhite),
+		Placeholder:   named(tcell.ColorGray),
+		PreviewBorder: named(tcell.ColorGreen),
+		ChromaStyle:   "monokai",
+		Bold:          true,
+	},
+	"light": {
+		Text:          named(tcell.ColorDefault),
+		Muted:         named(tcell.ColorGray),
+		Header:        named(tcell.ColorNavy),
+		Number:        named(tcell.ColorPurple),
+		Pinned:        named(tcell.ColorOlive),
+		Paused:        named(tcell.ColorMaroon),
+		Match:         named(tcell.ColorRed),
+		Marked:        named(tcell.ColorBlue),
+		Error:         named(tcell.ColorMaroon),
+		ListBorder:    named(tcell.ColorTeal),
+		HelpBorder:    named(tcell.ColorNavy),
+		HelpText:      named(tcell.ColorDefault),
+		SearchBorder:  named(tcell.ColorPurple),
+		SearchText:    named(tcell.ColorBlack),
+		Placeholder:   named(tcell.ColorGray),
+		PreviewBorder: named(tcell.ColorTeal),
+		ChromaStyle:   "github",
+		Bold:          true,
+	},
+	"high-contrast": {
+		Text:                named(tcell.ColorWhite),
+		Muted:               named(tcell.ColorWhite),
+		Header:              named(tcell.ColorYellow),
+		Number:              named(tcell.ColorAqua),
+		Pinned:              named(tcell.ColorYellow),
+		Paused:              named(tcell.ColorRed),
+		Match:               named(tcell.ColorLime),
+		Marked:              named(tcell.ColorFuchsia),
+		Error:               named(tcell.ColorRed),
+		SelectionText:       named(tcell.ColorBlack),
+		SelectionBackground: named(tcell.ColorYellow),
+		ListBorder:          named(tcell.ColorWhite),
+		HelpBorder:          named(tcell.ColorWhite),
+		HelpText:            named(tcell.ColorWhite),
+		SearchBorder:        named(tcell.ColorYellow),
+		SearchText:          named(tcell.ColorWhite),
+		Placeholder:         named(tcell.ColorSilver),
+		PreviewBorder:       named(tcell.ColorWhite),
+		ChromaStyle:         "hr_high_contrast",
+		Bold:                true,
+	},
+	NoColor: {Monochrome: true},
+}
+
+// Names lists the built-in themes
+func Names() []string {
+	names := make([]string, 0, len(builtin))
+	for name := range builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load returns the theme called name. A file name.toml in dir takes
+// precedence over a built-in theme of the same name. When NO_COLOR is set
+// the no-color theme is used regardless of name.
+func Load(name, dir string) (*Theme, error) {
+	if os.Getenv("NO_COLOR") != "" {
+		name = NoColor
+	}
+	return load(name, dir, nil)
+}
+
+// load resolves a theme and the themes it inherits from, with seen guarding
+// against inheritance cycles
+func load(name, dir string, seen []string) (*Theme, error) {
+	for _, s := range seen {
+		if s == name {
+			return nil, fmt.Errorf("theme %q inherits from itself", name)
+		}
+	}
+	seen = append(seen, name)
+
+	var data []byte
+	var err error
+	path := filepath.Join(dir, name+".toml")
+	if dir != "" {
+		data, err = os.ReadFile(path)
+	}
+	if dir == "" || errors.Is(err, os.ErrNotExist) {
+		if t, ok := builtin[name]; ok {
+			return &t, nil
+		}
+		return nil, fmt.Errorf("unknown theme %q, expected one of %s or a file in %s",
+			name, strings.Join(Names(), ", "), dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Find out what to inherit first, then decode the file over it
+	var header struct {
+		Inherit string `toml:"inherit"`
+	}
+	if _, err := toml.Decode(string(data), &header); err != nil {
+		return nil, fmt.Errorf("theme %s: %w", path, err)
+	}
+	parent := header.Inherit
+	if parent == "" {
+		parent = "dark"
+	}
+
+	var base *Theme
+	if parent == name {
+		// A file may tweak the built-in theme it shadows
+		t, ok := builtin[name]
+		if !ok {
+			return nil, fmt.Errorf("theme %q inherits from itself", name)
+		}
+		base = &t
+	} else {
+		base, err = load(parent, dir, seen)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t := *base
+	md, err := toml.Decode(string(data), &t)
+	if err != nil {
+		return nil, fmt.Errorf("theme %s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("theme %s: unknown key %s", path, undecoded[0])
+	}
+	return &t, nil
+}
+
+// SelectionStyle returns the style of the selected list row
+func (t *Theme) SelectionStyle() tcell.Style {
+	style := tcell.StyleDefault.
+		Foreground(t.SelectionText.Color).
+		Background(t.SelectionBackground.Color)
+	if t.SelectionText.Color == tcell.ColorDefault && t.SelectionBackground.Color == tcell.ColorDefault {
+		style = style.Reverse(true)
+	}
+	return style
+}
+
+// Emphasis returns the attributes for headers and numbers
+func (t *Theme) Emphasis() tcell.AttrMask {
+	if t.Bold {
+		return tcell.AttrBold
+	}
+	return tcell.AttrNone
+}
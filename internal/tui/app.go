@@ -3,62 +3,192 @@ package tui
 import (
 	"context"
 	"fmt"
+	"io"
+	"log"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/dvd/cliptui/internal/clipboard"
+	"github.com/dvd/cliptui/internal/config"
+	"github.com/dvd/cliptui/internal/keymap"
+	"github.com/dvd/cliptui/internal/language"
 	"github.com/dvd/cliptui/internal/search"
 	"github.com/dvd/cliptui/internal/storage"
+	"github.com/dvd/cliptui/internal/theme"
+	"github.com/dvd/cliptui/internal/typerules"
+	"github.com/dvd/cliptui/pkg/client"
 	"github.com/dvd/cliptui/pkg/types"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
 const (
-	// maxItemsToFetch is the maximum number of clipboard items to fetch from storage
-	maxItemsToFetch = 100
 	// clipboardPollInterval is how often to check for clipboard changes
 	clipboardPollInterval = 500 * time.Millisecond
 	// previewTruncateLength is the maximum length for list preview text
 	previewTruncateLength = 80
-	// previewFormatMaxLength is the maximum length for formatted preview content
+	// minContentWidth is the narrowest the list preview text gets
+	minContentWidth = 10
+	// pageMargins are the columns left blank around a page
+	pageMargins = 4
+	// listChrome is the width of everything in the list but the content:
+	// borders, padding, the number and date columns and their separators
+	listChrome = 24
+	// previewFormatMaxLength is the number of lines shown in the preview
+	// pane; the preview page shows all of them
 	previewFormatMaxLength = 1000
+	// previewAsyncSize is the content size from which the preview is
+	// formatted in the background, so that the UI does not freeze
+	previewAsyncSize = 64 << 10
+	// previewWindowLines is the number of lines of a long preview in the
+	// preview view at a time, around the lines shown
+	previewWindowLines = 2000
+	// previewWindowMargin is how near the lines shown get to an end of the
+	// window before it moves
+	previewWindowMargin = previewWindowLines / 4
 	// searchInputHeight is the height of the search input widget
 	searchInputHeight = 3
+	// autoSplitWidth is the terminal width from which an automatically
+	// placed preview pane goes right of the list instead of below it
+	autoSplitWidth = 120
 )
 
+// captureControl is implemented by stores backed by the daemon, which can
+// pause and resume clipboard capture
+type captureControl interface {
+	Pause(d time.Duration) error
+	Resume() error
+	Status() (*client.Status, error)
+}
+
 // AppState holds the application state
 type AppState struct {
 	mu            sync.RWMutex
-	storage       *storage.Storage
+	storage       storage.Store
 	items         []types.ClipboardItem
 	filteredItems []types.ClipboardItem
 	cursor        int
 	currentMode   mode
 	searchQuery   string
+	searchMode    search.Mode
+	// matches holds the matched byte offsets of each filtered item, by ID
+	matches map[int64][]int
+	// searchErr explains why the query could not be used, such as an
+	// invalid regular expression
+	searchErr string
+	// validQuery is the last query that could be used; it stays in effect
+	// while the query being typed has errors
+	validQuery  string
+	paused      bool
+	pausedUntil time.Time
+	// previewPane shows the selected item next to the list
+	previewPane bool
+	// previewID is the item in the preview view, 0 if none
+	previewID int64
+	// contentWidth is the width of the content column in the list
+	contentWidth int
+	// selected marks the items chosen for a bulk action, by ID
+	selected map[int64]bool
+	// visualAnchor is the item range selection started at, 0 when not
+	// selecting a range; visualBase is the selection from before it
+	visualAnchor int64
+	visualBase   map[int64]bool
+	// stayOpen keeps the TUI open after copying
+	stayOpen bool
+	// previewRaw shows items as they are instead of their rendered view
+	previewRaw bool
+	// lineNumbers numbers the lines of the preview
+	lineNumbers bool
+	// foldDepth folds the JSON objects and arrays nested this deep in the
+	// preview, 0 unfolds all
+	foldDepth int
+	// previewSearch is the text marked in the preview page
+	previewSearch string
+	// wrap wraps the long lines of the preview
+	wrap bool
 }
 
 // App represents the tview application
 type App struct {
-	app   *tview.Application
-	pages *tview.Pages
-	state *AppState
+	app    *tview.Application
+	pages  *tview.Pages
+	state  *AppState
+	config *config.Config
+
+	// keymaps resolves key presses to actions, per mode
+	keymaps map[string]*keymap.Keymap
+	theme   *theme.Theme
+	// typeRules define the custom types, which get a badge in the list
+	typeRules *typerules.Set
+	// debugLog records errors, it discards them unless SetDebugLog is called
+	debugLog *log.Logger
 
 	// Widgets
 	listWidget    *tview.Table
 	listContainer *tview.Flex
 	listHelp      *tview.TextView
 	searchInput   *tview.InputField
+	searchError   *tview.TextView
 	mainFlex      *tview.Flex
+	// promptInput asks for the argument of a bulk action
+	promptInput *tview.InputField
+	promptKind  prompt
+	// splitFlex holds the list and the preview pane
+	splitFlex *tview.Flex
 
 	previewView   *tview.TextView
 	previewHeader *tview.TextView
 	previewHelp   *tview.TextView
+	previewFlex   *tview.Flex
+	languageInput *tview.InputField
+	// previewInput asks for the text to search the preview for, or the
+	// line to go to
+	previewInput  *tview.InputField
+	previewPrompt prompt
+	// previewSeq counts preview updates, so that a preview formatted in the
+	// background does not replace a newer one
+	previewSeq int
+	// previewTitle is the title of the previewed item, without the matches
+	previewTitle string
+	// preview is the item formatted in the preview view, match the index
+	// of its highlighted search match or -1
+	preview Preview
+	match   int
+	// windowStart and windowEnd are the lines of the preview in the
+	// preview view
+	windowStart, windowEnd int
+	// lineRows holds the row every line of the window starts at, and the
+	// rows of the window, when lines wrap at rowsWidth; nil until needed
+	lineRows  []int
+	rowsWidth int
+
+	// statusLine shows the outcome of actions on both pages
+	statusLine *tview.TextView
+	// statusSeq counts status changes, so an expired message does not hide
+	// a newer one
+	statusSeq int
 }
 
 // New creates a new TUI application
-func New(store *storage.Storage) (*App, error) {
-	items, err := store.GetRecent(maxItemsToFetch)
+func New(store storage.Store, cfg *config.Config) (*App, error) {
+	items, err := store.List(types.Query{Limit: cfg.UI.ListLimit})
+	if err != nil {
+		return nil, err
+	}
+
+	th, err := theme.Load(cfg.UI.Theme, config.ThemesDir())
+	if err != nil {
+		return nil, err
+	}
+
+	searchMode, err := search.ParseMode(cfg.UI.SearchMode)
+	if err != nil {
+		return nil, err
+	}
+
+	typeRules, err := cfg.TypeRules()
 	if err != nil {
 		return nil, err
 	}
@@ -67,12 +197,16 @@ func New(store *storage.Storage) (*App, error) {
 	tview.Styles.PrimitiveBackgroundColor = tcell.ColorDefault
 	tview.Styles.ContrastBackgroundColor = tcell.ColorDefault
 	tview.Styles.MoreContrastBackgroundColor = tcell.ColorDefault
-	tview.Styles.PrimaryTextColor = tcell.ColorDefault
+	tview.Styles.PrimaryTextColor = th.Text.Color
 	tview.Styles.InverseTextColor = tcell.ColorDefault
 	tview.Styles.ContrastSecondaryTextColor = tcell.ColorDefault
 
 	app := &App{
-		app: tview.NewApplication(),
+		app:       tview.NewApplication(),
+		config:    cfg,
+		theme:     th,
+		typeRules: typeRules,
+		debugLog:  log.New(io.Discard, "", 0),
 		state: &AppState{
 			storage:       store,
 			items:         items,
@@ -80,23 +214,40 @@ func New(store *storage.Storage) (*App, error) {
 			cursor:        0,
 			currentMode:   modeList,
 			searchQuery:   "",
+			searchMode:    searchMode,
+			previewPane:   cfg.UI.PreviewPane,
+			stayOpen:      cfg.UI.StayOpen,
+			lineNumbers:   cfg.UI.LineNumbers,
+			wrap:          cfg.UI.Wrap,
+			contentWidth:  previewTruncateLength,
 		},
 	}
 
+	if err := app.buildKeymaps(); err != nil {
+		return nil, err
+	}
+
 	app.pages = tview.NewPages()
+	app.buildStatusLine()
 
-	listPage := app.buildListPage()
+	// The list page shares the preview view as its preview pane
 	previewPage := app.buildPreviewPage()
+	listPage := app.buildListPage()
 
 	app.pages.AddPage("list", listPage, true, true)
 	app.pages.AddPage("preview", previewPage, true, false)
 
 	app.app.SetRoot(app.pages, true)
+	app.app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		app.slideWindow()
+		return app.fitLayout(screen)
+	})
 	app.setupGlobalKeys()
 
 	// Enable mouse capture (prevents terminal text selection, enables mouse events)
-	app.app.EnableMouse(true)
+	app.app.EnableMouse(cfg.UI.Mouse)
 
+	app.resizePreviewPane()
 	app.updateListDisplay()
 
 	return app, nil
@@ -126,24 +277,22 @@ func (a *App) monitorClipboard(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			items, err := a.state.storage.GetRecent(maxItemsToFetch)
+			pauseChanged := a.refreshCaptureState()
+
+			items, err := a.state.storage.List(types.Query{Limit: a.config.UI.ListLimit})
 			if err != nil {
+				// Not shown, the next tick is likely to fail the same way
+				a.debugLog.Printf("refresh the history: %v", err)
 				continue
 			}
 
 			a.state.mu.Lock()
-			needsUpdate := len(items) != len(a.state.items) ||
+			needsUpdate := pauseChanged || len(items) != len(a.state.items) ||
 				(len(items) > 0 && len(a.state.items) > 0 && items[0].ID != a.state.items[0].ID)
 
 			if needsUpdate {
 				a.state.items = items
-
-				// Update filtered items if not searching
-				if a.state.searchQuery == "" {
-					a.state.filteredItems = items
-				} else {
-					a.state.filteredItems = search.Filter(items, a.state.searchQuery)
-				}
+				a.filterItems()
 
 				if a.state.cursor >= len(a.state.filteredItems) {
 					a.state.cursor = len(a.state.filteredItems) - 1
@@ -151,6 +300,7 @@ func (a *App) monitorClipboard(ctx context.Context) {
 				if a.state.cursor < 0 {
 					a.state.cursor = 0
 				}
+				a.pruneSelection()
 			}
 			a.state.mu.Unlock()
 
@@ -163,6 +313,29 @@ func (a *App) monitorClipboard(ctx context.Context) {
 	}
 }
 
+// refreshCaptureState asks the daemon whether capture is paused and reports
+// whether the title needs redrawing, either because the state changed or to
+// count down a timed pause
+func (a *App) refreshCaptureState() bool {
+	control, ok := a.state.storage.(captureControl)
+	if !ok {
+		return false
+	}
+
+	status, err := control.Status()
+	if err != nil {
+		return false
+	}
+
+	a.state.mu.Lock()
+	defer a.state.mu.Unlock()
+
+	changed := a.state.paused != status.Paused || !a.state.pausedUntil.Equal(status.PausedUntil)
+	a.state.paused = status.Paused
+	a.state.pausedUntil = status.PausedUntil
+	return changed || (status.Paused && !status.PausedUntil.IsZero())
+}
+
 // setupGlobalKeys sets up global keyboard shortcuts
 func (a *App) setupGlobalKeys() {
 	a.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -172,12 +345,6 @@ func (a *App) setupGlobalKeys() {
 			return nil
 		}
 
-		// Mode-specific 'q' quit
-		if event.Rune() == 'q' && a.state.currentMode != modeSearch {
-			a.app.Stop()
-			return nil
-		}
-
 		return event
 	})
 }
@@ -186,11 +353,17 @@ func (a *App) setupGlobalKeys() {
 func (a *App) switchToListMode() {
 	a.state.mu.Lock()
 	a.state.currentMode = modeList
+	a.state.previewSearch = ""
+	open := a.state.previewPane
 	a.state.mu.Unlock()
 
 	a.pages.SwitchToPage("list")
 	a.app.SetFocus(a.listWidget)
 	a.updateListDisplay()
+	if open {
+		// Back to the pane's shortened preview, without the search
+		a.updatePreviewContent()
+	}
 }
 
 // switchToPreviewMode switches to preview mode
@@ -224,6 +397,7 @@ func (a *App) switchToSearchMode() {
 	// Replace help with search input
 	a.mainFlex.RemoveItem(a.listHelp)
 	a.mainFlex.AddItem(a.searchInput, searchInputHeight, 0, true)
+	a.mainFlex.AddItem(a.searchError, 0, 0, false)
 
 	a.app.SetFocus(a.searchInput)
 }
@@ -236,13 +410,15 @@ func (a *App) exitSearchMode() {
 
 	// Replace search input with help
 	a.mainFlex.RemoveItem(a.searchInput)
+	a.mainFlex.RemoveItem(a.searchError)
 	a.mainFlex.AddItem(a.listHelp, searchInputHeight, 0, false)
 
 	a.app.SetFocus(a.listWidget)
 }
 
-// handleCopyAction copies the selected item and quits
-func (a *App) handleCopyAction() {
+// switchToLanguageInput replaces the preview help with a prompt for the
+// language of the previewed item
+func (a *App) switchToLanguageInput() {
 	a.state.mu.RLock()
 	if len(a.state.filteredItems) == 0 {
 		a.state.mu.RUnlock()
@@ -251,52 +427,256 @@ func (a *App) handleCopyAction() {
 	item := a.state.filteredItems[a.state.cursor]
 	a.state.mu.RUnlock()
 
-	clipboard.SetClipboard(item.Content)
-	a.app.Stop()
+	placeholder := "Language, empty to detect it"
+	if item.Language != "" {
+		placeholder = fmt.Sprintf("%s, empty to detect it again", item.Language)
+	}
+	a.languageInput.SetText("")
+	a.languageInput.SetPlaceholder(placeholder)
+	a.languageInput.SetTitle(" Language (enter to set, esc to cancel) ")
+	a.previewFlex.RemoveItem(a.previewHelp)
+	a.previewFlex.AddItem(a.languageInput, searchInputHeight, 0, true)
+	a.app.SetFocus(a.languageInput)
 }
 
-// handleDeleteAction deletes the selected item
-func (a *App) handleDeleteAction() {
+// exitLanguageInput puts the preview help back in place of the language prompt
+func (a *App) exitLanguageInput() {
+	a.previewFlex.RemoveItem(a.languageInput)
+	a.previewFlex.AddItem(a.previewHelp, searchInputHeight, 0, false)
+	a.app.SetFocus(a.previewView)
+}
+
+// handleLanguageDone stores the language typed for the previewed item.
+// Choosing a language makes the item code; an empty language detects it again.
+func (a *App) handleLanguageDone(key tcell.Key) {
+	if key != tcell.KeyEnter {
+		a.exitLanguageInput()
+		return
+	}
+
+	a.state.mu.RLock()
+	if len(a.state.filteredItems) == 0 {
+		a.state.mu.RUnlock()
+		a.exitLanguageInput()
+		return
+	}
+	item := a.state.filteredItems[a.state.cursor]
+	a.state.mu.RUnlock()
+
+	lang := language.Detect(item.Content)
+	if text := strings.TrimSpace(a.languageInput.GetText()); text != "" {
+		name, ok := language.Lookup(text)
+		if !ok {
+			a.languageInput.SetTitle(tview.Escape(fmt.Sprintf(" Unknown language %q ", text)))
+			return
+		}
+		lang = name
+	}
+
+	if item.Type != types.TypeCode {
+		if err := a.state.storage.SetType(item.ID, types.TypeCode); err != nil {
+			a.fail(fmt.Sprintf("make item %d code", item.ID), err)
+		}
+	}
+	if err := a.state.storage.SetLanguage(item.ID, lang); err != nil {
+		a.fail(fmt.Sprintf("set the language of item %d", item.ID), err)
+	}
+	a.exitLanguageInput()
+	a.reloadItems()
+	a.updateListDisplay()
+	a.updatePreviewContent()
+}
+
+// handleCopyAction copies the selected item. It quits unless stay-open
+// mode is on and quit is not set.
+func (a *App) handleCopyAction(quit bool) {
 	a.state.mu.RLock()
 	if len(a.state.filteredItems) == 0 {
 		a.state.mu.RUnlock()
 		return
 	}
-	itemID := a.state.filteredItems[a.state.cursor].ID
+	item := a.state.filteredItems[a.state.cursor]
+	a.state.mu.RUnlock()
+
+	a.copyItem(item, quit)
+}
+
+// copyItem copies an item and quits. In stay-open mode, unless quit is
+// set, the TUI stays open and the item moves to the top of the history.
+func (a *App) copyItem(item types.ClipboardItem, quit bool) {
+	a.state.mu.RLock()
+	stay := a.state.stayOpen && !quit
 	a.state.mu.RUnlock()
 
-	a.state.storage.Delete(itemID)
+	if !stay {
+		if a.copyToClipboard(item.Content, fmt.Sprintf("item %d", item.ID)) {
+			a.app.Stop()
+		}
+		return
+	}
+
+	// Touch first, so the daemon finds the copied content at the top and
+	// does not store it again
+	if err := a.state.storage.Touch(item.ID); err != nil {
+		a.fail(fmt.Sprintf("move item %d to the top", item.ID), err)
+	}
+	a.copyToClipboard(item.Content, fmt.Sprintf("item %d", item.ID))
+
 	a.reloadItems()
+	a.state.mu.Lock()
+	if i := slices.IndexFunc(a.state.filteredItems, func(it types.ClipboardItem) bool { return it.ID == item.ID }); i >= 0 {
+		a.state.cursor = i
+	}
+	currentMode := a.state.currentMode
+	a.state.mu.Unlock()
+
 	a.updateListDisplay()
+	if currentMode == modePreview {
+		a.updatePreviewContent()
+	}
 }
 
-// handleClearAllAction clears all clipboard history
+// toggleStayOpen switches between quitting and staying open after a copy
+func (a *App) toggleStayOpen() {
+	a.state.mu.Lock()
+	a.state.stayOpen = !a.state.stayOpen
+	stayOpen := a.state.stayOpen
+	a.state.mu.Unlock()
+
+	if stayOpen {
+		a.notify("Copying keeps ClipTUI open")
+	} else {
+		a.notify("Copying quits ClipTUI")
+	}
+	a.updateListDisplay()
+}
+
+// handlePinAction pins the selected items, or unpins them when they are
+// all pinned
+func (a *App) handlePinAction() {
+	targets := a.targets()
+	if len(targets) == 0 {
+		return
+	}
+	pinned := slices.ContainsFunc(targets, func(item types.ClipboardItem) bool { return !item.Pinned })
+
+	verb := "pin"
+	if !pinned {
+		verb = "unpin"
+	}
+	done := 0
+	for _, item := range targets {
+		if err := a.state.storage.SetPinned(item.ID, pinned); err != nil {
+			a.fail(fmt.Sprintf("%s item %d", verb, item.ID), err)
+			break
+		}
+		done++
+	}
+	if done == len(targets) {
+		a.notify("%sned %s", strings.ToUpper(verb[:1])+verb[1:], plural(done, "item"))
+	}
+	a.clearSelection()
+	a.reloadItems()
+	a.updateListDisplay()
+}
+
+// handleDeleteAction deletes the selected items, after asking when there
+// is more than one
+func (a *App) handleDeleteAction() {
+	targets := a.targets()
+	switch {
+	case len(targets) == 1:
+		a.deleteItems(targets)
+	case len(targets) > 1:
+		a.confirm(fmt.Sprintf("Delete %s?", plural(len(targets), "item")), "Delete", func() {
+			a.deleteItems(targets)
+		})
+	}
+}
+
+// deleteItems deletes items from the history
+func (a *App) deleteItems(items []types.ClipboardItem) {
+	done := 0
+	for _, item := range items {
+		if err := a.state.storage.Delete(item.ID); err != nil {
+			a.fail(fmt.Sprintf("delete item %d", item.ID), err)
+			break
+		}
+		done++
+	}
+	if done == len(items) {
+		a.notify("Deleted %s", plural(done, "item"))
+	}
+	a.clearSelection()
+	a.reloadItems()
+	a.updateListDisplay()
+}
+
+// handleClearAllAction asks before clearing all clipboard history
 func (a *App) handleClearAllAction() {
-	a.state.storage.Clear()
+	a.confirm("Clear the whole clipboard history? Pinned items are deleted too.", "Clear", a.clearHistory)
+}
+
+// clearHistory deletes every item
+func (a *App) clearHistory() {
+	if err := a.state.storage.Clear(); err != nil {
+		a.fail("clear the history", err)
+		a.reloadItems()
+		a.updateListDisplay()
+		return
+	}
+	a.notify("Cleared the history")
 
 	a.state.mu.Lock()
 	a.state.items = []types.ClipboardItem{}
 	a.state.filteredItems = []types.ClipboardItem{}
 	a.state.cursor = 0
+	a.state.selected = nil
+	a.state.visualAnchor = 0
+	a.state.visualBase = nil
 	a.state.mu.Unlock()
 
 	a.updateListDisplay()
 }
 
+// handleTogglePauseAction pauses or resumes the daemon's clipboard capture
+func (a *App) handleTogglePauseAction() {
+	control, ok := a.state.storage.(captureControl)
+	if !ok {
+		return
+	}
+
+	a.state.mu.RLock()
+	paused := a.state.paused
+	a.state.mu.RUnlock()
+
+	if paused {
+		if err := control.Resume(); err != nil {
+			a.fail("resume capture", err)
+		}
+	} else {
+		if err := control.Pause(0); err != nil {
+			a.fail("pause capture", err)
+		}
+	}
+
+	a.refreshCaptureState()
+	a.updateListDisplay()
+}
+
 // reloadItems reloads items from storage
 func (a *App) reloadItems() {
-	items, _ := a.state.storage.GetRecent(maxItemsToFetch)
+	items, err := a.state.storage.List(types.Query{Limit: a.config.UI.ListLimit})
+	if err != nil {
+		a.fail("load the history", err)
+		return
+	}
 
 	a.state.mu.Lock()
 	defer a.state.mu.Unlock()
 
 	a.state.items = items
-
-	if a.state.searchQuery != "" {
-		a.state.filteredItems = search.Filter(items, a.state.searchQuery)
-	} else {
-		a.state.filteredItems = items
-	}
+	a.filterItems()
 
 	if a.state.cursor >= len(a.state.filteredItems) {
 		a.state.cursor = len(a.state.filteredItems) - 1
@@ -304,6 +684,38 @@ func (a *App) reloadItems() {
 	if a.state.cursor < 0 {
 		a.state.cursor = 0
 	}
+	a.pruneSelection()
+}
+
+// filterItems applies the search query to the loaded items. The caller
+// must hold a.state.mu.
+func (a *App) filterItems() {
+	a.state.matches = nil
+	a.state.searchErr = ""
+	if a.state.searchQuery == "" {
+		a.state.filteredItems = a.state.items
+		a.state.validQuery = ""
+		return
+	}
+
+	results, err := search.Search(a.state.items, a.state.searchQuery, a.state.searchMode)
+	if err != nil {
+		a.state.searchErr = err.Error()
+		results, err = search.Search(a.state.items, a.state.validQuery, a.state.searchMode)
+		if err != nil {
+			// The valid query may not be valid in this search mode
+			results, _ = search.Search(a.state.items, "", a.state.searchMode)
+		}
+	} else {
+		a.state.validQuery = a.state.searchQuery
+	}
+
+	a.state.filteredItems = make([]types.ClipboardItem, len(results))
+	a.state.matches = make(map[int64][]int, len(results))
+	for i, r := range results {
+		a.state.filteredItems[i] = r.Item
+		a.state.matches[r.Item.ID] = r.Positions
+	}
 }
 
 // updateListDisplay updates the table widget with current items
@@ -314,28 +726,56 @@ func (a *App) updateListDisplay() {
 	filteredItems := a.state.filteredItems
 	cursor := a.state.cursor
 	searchQuery := a.state.searchQuery
+	searchErr := a.state.searchErr
+	matches := a.state.matches
 	currentMode := a.state.currentMode
+	paused := a.state.paused
+	pausedUntil := a.state.pausedUntil
+	contentWidth := a.state.contentWidth
+	selected := maps.Clone(a.state.selected)
+	visual := a.state.visualAnchor != 0
+	stayOpen := a.state.stayOpen
 	a.state.mu.RUnlock()
 
+	var title string
 	if len(filteredItems) > 0 {
-		title := fmt.Sprintf(" Clipboard History (%d/%d) ",
+		title = fmt.Sprintf(" Clipboard History (%d/%d) ",
 			cursor+1, len(filteredItems))
-		a.listContainer.SetTitle(title)
 	} else {
-		a.listContainer.SetTitle(" Clipboard History (0) ")
+		title = " Clipboard History (0) "
+	}
+	if len(selected) > 0 || visual {
+		title += fmt.Sprintf("[%s]%d selected[-] ", a.theme.Marked.Tag(), len(selected))
+	}
+	if visual {
+		title += fmt.Sprintf("[%s]VISUAL[-] ", a.theme.Marked.Tag())
+	}
+	if stayOpen {
+		title += fmt.Sprintf("[%s]STAY OPEN[-] ", a.theme.Muted.Tag())
+	}
+	if paused {
+		title += formatPaused(pausedUntil, a.theme.Paused) + " "
+	}
+	a.listContainer.SetTitle(title)
+
+	a.searchError.SetText(searchErr)
+	if searchErr != "" {
+		a.mainFlex.ResizeItem(a.searchError, 1, 0)
+	} else {
+		a.mainFlex.ResizeItem(a.searchError, 0, 0)
 	}
 
 	if len(filteredItems) == 0 {
 		var message string
 		if searchQuery != "" {
-			message = "No results found for '" + searchQuery + "'"
+			message = "No results found for '" + tview.Escape(searchQuery) + "'"
 		} else {
 			message = "No items in clipboard history"
 		}
 
 		a.listWidget.SetCell(0, 0, tview.NewTableCell(message).
 			SetAlign(tview.AlignCenter).
-			SetTextColor(tcell.ColorDefault).
+			SetTextColor(a.theme.Muted.Color).
 			SetAttributes(tcell.AttrDim).
 			SetSelectable(false))
 		return
@@ -347,25 +787,25 @@ func (a *App) updateListDisplay() {
 		SetSelectable(false))
 	// Number column
 	a.listWidget.SetCell(0, 1, tview.NewTableCell(" # ").
-		SetTextColor(tcell.ColorYellow).
+		SetTextColor(a.theme.Header.Color).
 		SetAlign(tview.AlignCenter).
 		SetSelectable(false).
 		SetExpansion(0).
-		SetAttributes(tcell.AttrBold))
+		SetAttributes(a.theme.Emphasis()))
 	// Content column
 	a.listWidget.SetCell(0, 2, tview.NewTableCell("Content").
-		SetTextColor(tcell.ColorYellow).
+		SetTextColor(a.theme.Header.Color).
 		SetAlign(tview.AlignLeft).
 		SetSelectable(false).
 		SetExpansion(3).
-		SetAttributes(tcell.AttrBold))
+		SetAttributes(a.theme.Emphasis()))
 	// Date column
 	a.listWidget.SetCell(0, 3, tview.NewTableCell(fmt.Sprintf("%12s", "Date")).
-		SetTextColor(tcell.ColorYellow).
+		SetTextColor(a.theme.Header.Color).
 		SetAlign(tview.AlignRight).
 		SetSelectable(false).
 		SetExpansion(0).
-		SetAttributes(tcell.AttrBold))
+		SetAttributes(a.theme.Emphasis()))
 	// Right spacer
 	a.listWidget.SetCell(0, 4, tview.NewTableCell("").
 		SetExpansion(2).
@@ -373,7 +813,17 @@ func (a *App) updateListDisplay() {
 
 	for i, item := range filteredItems {
 		row := i + 1 // +1 because row 0 is the header
-		preview := truncate(item.Preview, previewTruncateLength)
+		badges, badgesWidth := a.itemBadges(item)
+		text, offsets := types.SingleLineOffsets(item.Preview, max(contentWidth-badgesWidth, minContentWidth))
+		// Only the part of the preview copied from the content can match
+		limit := len(item.Preview)
+		switch {
+		case item.Type == types.TypeBinary:
+			limit = 0 // the placeholder is not part of the content
+		case item.Preview != item.Content:
+			limit -= len("...") // nor is the ellipsis
+		}
+		preview := badges + highlightMatches(text, offsets, matches[item.ID], limit, a.theme.Match)
 		timestamp := formatTimestamp(item.Timestamp)
 
 		// Left spacer
@@ -387,23 +837,26 @@ func (a *App) updateListDisplay() {
 		} else {
 			numStr = "   "
 		}
+		if selected[item.ID] {
+			numStr = fmt.Sprintf("[%s]●[-]%s", a.theme.Marked.Tag(), numStr[1:])
+		}
 		a.listWidget.SetCell(row, 1, tview.NewTableCell(numStr).
 			SetAlign(tview.AlignCenter).
-			SetTextColor(tcell.ColorYellow).
+			SetTextColor(a.theme.Number.Color).
 			SetExpansion(0).
-			SetAttributes(tcell.AttrBold))
+			SetAttributes(a.theme.Emphasis()))
 
 		// Content column
 		a.listWidget.SetCell(row, 2, tview.NewTableCell(preview).
 			SetAlign(tview.AlignLeft).
 			SetExpansion(3).
-			SetTextColor(tcell.ColorDefault))
+			SetTextColor(a.theme.Text.Color))
 
 		// Date column
 		a.listWidget.SetCell(row, 3, tview.NewTableCell(fmt.Sprintf("%12s", timestamp)).
 			SetAlign(tview.AlignRight).
 			SetExpansion(0).
-			SetTextColor(tcell.ColorDefault).
+			SetTextColor(a.theme.Muted.Color).
 			SetAttributes(tcell.AttrDim))
 
 		// Right spacer
@@ -414,26 +867,259 @@ func (a *App) updateListDisplay() {
 	if len(filteredItems) > 0 && currentMode != modeSearch {
 		a.listWidget.Select(cursor+1, 1) // +1 for header row, column 1 is number column
 	}
+	a.updatePreviewPane()
+}
+
+// itemBadges returns the markers shown before the content of an item in the
+// list, for pinned items, custom types and the language of code, and their
+// width
+func (a *App) itemBadges(item types.ClipboardItem) (string, int) {
+	var tagged, plain strings.Builder
+	badge := func(style, text string) {
+		fmt.Fprintf(&tagged, "%s%s[-::-] ", style, tview.Escape(text))
+		plain.WriteString(text + " ")
+	}
+
+	if item.Pinned {
+		badge(fmt.Sprintf("[%s]", a.theme.Pinned.Tag()), "★")
+	}
+	if color, ok := a.typeRules.Color(item.Type); ok {
+		if color.Tag() == "-" {
+			color = a.theme.Header
+		}
+		badge(fmt.Sprintf("[%s::b]", color.Tag()), item.Type)
+	}
+	if item.Type == types.TypeCode && item.Language != "" {
+		badge(fmt.Sprintf("[%s::d]", a.theme.Muted.Tag()), strings.ToLower(item.Language))
+	}
+	return tagged.String(), types.StringWidth(plain.String())
 }
 
 // updatePreviewContent updates the preview view with current item
 func (a *App) updatePreviewContent() {
+	a.renderPreview(nil)
+}
+
+// renderPreview shows the current item in the preview view and runs done,
+// if set, once it is shown. Large items are formatted in the background
+// while the view says they are loading. A new item is shown from the top,
+// the item already shown keeps its scroll position.
+func (a *App) renderPreview(done func()) {
+	a.previewSeq++
+	seq := a.previewSeq
+
 	a.state.mu.RLock()
 	if len(a.state.filteredItems) == 0 || a.state.cursor >= len(a.state.filteredItems) {
 		a.state.mu.RUnlock()
+		a.setPreviewID(0)
+		a.previewTitle = " Preview "
+		a.preview = Preview{}
+		a.setWindow(0)
+		a.previewView.SetTitle(a.previewTitle)
 		a.previewView.SetText("No item selected")
 		return
 	}
 	item := a.state.filteredItems[a.state.cursor]
 	a.state.mu.RUnlock()
+	changed := a.setPreviewID(item.ID)
+
+	a.state.mu.RLock()
+	opts := PreviewOptions{
+		Raw:         a.state.previewRaw,
+		LineNumbers: a.state.lineNumbers,
+		FoldDepth:   a.state.foldDepth,
+		MaxLines:    previewFormatMaxLength,
+	}
+	if a.state.currentMode == modePreview {
+		opts.MaxLines = 0
+		opts.Search = a.state.previewSearch
+	}
+	a.state.mu.RUnlock()
 
 	timestamp := formatTimestamp(item.Timestamp)
+	itemType := item.Type
+	if item.Type == types.TypeCode && item.Language != "" {
+		itemType = fmt.Sprintf("%s (%s)", item.Type, item.Language)
+	}
 	title := fmt.Sprintf(" Preview - %s • %d bytes • %s ",
-		item.Type, len(item.Content), timestamp)
-	a.previewView.SetTitle(title)
+		itemType, len(item.Content), timestamp)
+
+	show := func(preview Preview) {
+		if seq != a.previewSeq {
+			return
+		}
+		if preview.View != "" {
+			title += "• " + preview.View + " "
+		}
+		a.previewTitle = tview.Escape(title)
+		a.match = -1
+		a.previewView.Highlight()
+		if changed {
+			a.preview = preview
+			a.scrollToBeginning()
+		} else {
+			// Keep the line at the top in place
+			row, col := a.previewView.GetScrollOffset()
+			line, lineRow := a.rowLine(row)
+			a.preview = preview
+			a.setWindow(line)
+			a.previewView.ScrollTo(a.lineRow(line)+lineRow, col)
+		}
+		a.updateMatchTitle()
+		if done != nil {
+			done()
+		}
+	}
+
+	if len(item.Content) < previewAsyncSize {
+		show(FormatPreview(item, opts, a.theme))
+		return
+	}
+	a.previewView.SetTitle(tview.Escape(title + "• loading "))
+	if changed {
+		a.preview = Preview{}
+		a.setWindow(0)
+		a.previewView.SetText("")
+	}
+	go func() {
+		preview := FormatPreview(item, opts, a.theme)
+		a.app.QueueUpdateDraw(func() {
+			show(preview)
+		})
+	}()
+}
+
+// setPreviewID records which item the preview view shows and reports
+// whether it is another one. JSON folding and the search start over for
+// every item.
+func (a *App) setPreviewID(id int64) bool {
+	a.state.mu.Lock()
+	defer a.state.mu.Unlock()
+	if id == a.state.previewID {
+		return false
+	}
+	a.state.foldDepth = 0
+	a.state.previewSearch = ""
+	a.state.previewID = id
+	return true
+}
 
-	content := FormatPreview(item.Content, item.Type, previewFormatMaxLength)
-	a.previewView.SetText(content)
-	a.previewView.ScrollToBeginning()
+// togglePreviewView switches the preview between the rendered view of the
+// item and its raw content
+func (a *App) togglePreviewView() {
+	a.state.mu.Lock()
+	a.state.previewRaw = !a.state.previewRaw
+	a.state.mu.Unlock()
+	a.updatePreviewContent()
 }
 
+// toggleLineNumbers shows or hides the line numbers of the preview
+func (a *App) toggleLineNumbers() {
+	a.state.mu.Lock()
+	a.state.lineNumbers = !a.state.lineNumbers
+	a.state.mu.Unlock()
+	a.updatePreviewContent()
+}
+
+// foldPreview folds JSON in the preview one level further, or unfolds it
+// one level for a positive step
+func (a *App) foldPreview(step int) {
+	a.state.mu.Lock()
+	if len(a.state.filteredItems) == 0 {
+		a.state.mu.Unlock()
+		return
+	}
+	item := a.state.filteredItems[a.state.cursor]
+	if item.Type != types.TypeJSON || a.state.previewRaw {
+		a.state.mu.Unlock()
+		return
+	}
+
+	deepest := jsonFoldDepth(item.Content)
+	depth := a.state.foldDepth
+	if depth == 0 {
+		depth = deepest
+	}
+	depth = min(max(depth+step, 1), deepest)
+	if depth >= deepest {
+		depth = 0
+	}
+	a.state.foldDepth = depth
+	a.state.mu.Unlock()
+
+	a.updatePreviewContent()
+}
+
+// updatePreviewPane shows the selected item in the preview pane, if the
+// pane is open and shows another item
+func (a *App) updatePreviewPane() {
+	a.state.mu.RLock()
+	open := a.state.previewPane
+	var id int64
+	if a.state.cursor < len(a.state.filteredItems) {
+		id = a.state.filteredItems[a.state.cursor].ID
+	}
+	stale := id != a.state.previewID
+	a.state.mu.RUnlock()
+
+	if open && stale {
+		a.updatePreviewContent()
+	}
+}
+
+// togglePreviewPane opens or closes the preview pane
+func (a *App) togglePreviewPane() {
+	a.state.mu.Lock()
+	a.state.previewPane = !a.state.previewPane
+	a.state.mu.Unlock()
+
+	a.resizePreviewPane()
+	a.updatePreviewPane()
+}
+
+// resizePreviewPane gives the preview pane its configured share of the
+// list page, or none while it is closed
+func (a *App) resizePreviewPane() {
+	a.state.mu.RLock()
+	open := a.state.previewPane
+	a.state.mu.RUnlock()
+
+	ratio := 0
+	if open {
+		ratio = a.config.UI.PreviewRatio
+	}
+	a.splitFlex.ResizeItem(a.listContainer, 0, 100-ratio)
+	a.splitFlex.ResizeItem(a.previewView, 0, ratio)
+}
+
+// fitLayout runs before every draw. It puts the preview pane right of the
+// list or below it, following the terminal width when placed automatically,
+// and shortens the content column so that the dates fit next to it.
+func (a *App) fitLayout(screen tcell.Screen) bool {
+	width, _ := screen.Size()
+	direction := tview.FlexRow
+	switch a.config.UI.PreviewPosition {
+	case config.PreviewRight:
+		direction = tview.FlexColumn
+	case config.PreviewAuto:
+		if width >= autoSplitWidth {
+			direction = tview.FlexColumn
+		}
+	}
+	a.splitFlex.SetDirection(direction)
+
+	a.state.mu.Lock()
+	listWidth := width - pageMargins
+	if a.state.previewPane && direction == tview.FlexColumn {
+		listWidth = listWidth * (100 - a.config.UI.PreviewRatio) / 100
+	}
+	contentWidth := min(max(listWidth-listChrome, minContentWidth), previewTruncateLength)
+	changed := contentWidth != a.state.contentWidth
+	a.state.contentWidth = contentWidth
+	a.state.mu.Unlock()
+
+	if changed {
+		a.updateListDisplay()
+	}
+	return false
+}
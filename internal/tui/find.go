@@ -0,0 +1,284 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// switchToPreviewPrompt replaces the preview help with a prompt for the
+// text to search the preview for or the line to go to
+func (a *App) switchToPreviewPrompt(kind prompt) {
+	a.state.mu.RLock()
+	query := a.state.previewSearch
+	a.state.mu.RUnlock()
+
+	switch kind {
+	case promptFind:
+		a.previewInput.SetText(query)
+		a.previewInput.SetTitle(" Search (enter to find, esc to cancel) ")
+		a.previewInput.SetPlaceholder("Text to find, ignoring case unless it has capitals; empty to clear")
+		a.previewInput.SetAcceptanceFunc(nil)
+	case promptLine:
+		a.previewInput.SetText("")
+		a.previewInput.SetTitle(" Go to line (enter to go, esc to cancel) ")
+		a.previewInput.SetPlaceholder(fmt.Sprintf("Line number, 1 to %d", a.preview.Lines()))
+		a.previewInput.SetAcceptanceFunc(tview.InputFieldInteger)
+	}
+
+	a.previewPrompt = kind
+	a.previewFlex.RemoveItem(a.previewHelp)
+	a.previewFlex.AddItem(a.previewInput, searchInputHeight, 0, true)
+	a.app.SetFocus(a.previewInput)
+}
+
+// exitPreviewPrompt puts the preview help back in place of the prompt
+func (a *App) exitPreviewPrompt() {
+	a.previewFlex.RemoveItem(a.previewInput)
+	a.previewFlex.AddItem(a.previewHelp, searchInputHeight, 0, false)
+	a.app.SetFocus(a.previewView)
+}
+
+// handlePreviewPromptDone searches the preview or goes to the line typed in
+// the prompt. The prompt stays open when the line is not a number.
+func (a *App) handlePreviewPromptDone(key tcell.Key) {
+	if key != tcell.KeyEnter {
+		a.exitPreviewPrompt()
+		return
+	}
+
+	text := a.previewInput.GetText()
+	switch a.previewPrompt {
+	case promptFind:
+		a.exitPreviewPrompt()
+		a.searchPreview(text)
+	case promptLine:
+		line, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil || line < 1 {
+			a.previewInput.SetTitle(tview.Escape(fmt.Sprintf(" Not a line number: %q ", text)))
+			return
+		}
+		a.exitPreviewPrompt()
+		a.gotoLine(line)
+	}
+}
+
+// searchPreview marks the matches of query in the preview and shows the
+// first one, or clears the search when query is empty
+func (a *App) searchPreview(query string) {
+	a.state.mu.Lock()
+	a.state.previewSearch = query
+	a.state.mu.Unlock()
+
+	a.renderPreview(func() {
+		if query != "" {
+			a.showMatch(1)
+		}
+	})
+}
+
+// showMatch highlights the search match step matches after the highlighted
+// one, wrapping around at either end, and scrolls it to the middle of the
+// view. Without a highlighted match, a positive step shows the first and a
+// negative one the last.
+func (a *App) showMatch(step int) {
+	a.state.mu.RLock()
+	query := a.state.previewSearch
+	a.state.mu.RUnlock()
+
+	if query == "" {
+		return
+	}
+	count := len(a.preview.Matches)
+	if count == 0 {
+		a.notify("No match for %s", query)
+		return
+	}
+
+	switch {
+	case a.match >= 0:
+		a.match = ((a.match+step)%count + count) % count
+	case step < 0:
+		a.match = count - 1
+	default:
+		a.match = 0
+	}
+	// The region of the match is only highlighted when it is in the window
+	m := a.preview.Matches[a.match]
+	a.revealLine(m.Line)
+	a.previewView.Highlight(fmt.Sprintf("m%d", a.match))
+	a.updateMatchTitle()
+
+	// The text view scrolls to highlights itself, but draws only part of
+	// the view when they are near the top
+	_, _, width, height := a.previewView.GetInnerRect()
+	row, col := a.lineRow(m.Line), 0
+	if a.wrapped() && width > 0 {
+		row += m.Column / width
+	} else if m.Column >= width {
+		col = m.Column - width/4
+	}
+	a.previewView.ScrollTo(max(row-height/2, 0), col)
+}
+
+// updateMatchTitle shows the number of search matches, and which one is
+// highlighted, in the preview title
+func (a *App) updateMatchTitle() {
+	a.state.mu.RLock()
+	query := a.state.previewSearch
+	a.state.mu.RUnlock()
+
+	title := a.previewTitle
+	switch {
+	case query == "":
+	case len(a.preview.Matches) == 0:
+		title += "• no matches "
+	case a.match < 0:
+		title += fmt.Sprintf("• %d matches ", len(a.preview.Matches))
+	default:
+		title += fmt.Sprintf("• match %d of %d ", a.match+1, len(a.preview.Matches))
+	}
+	a.previewView.SetTitle(title)
+}
+
+// gotoLine scrolls the preview to put a line of the text shown, counted
+// from 1, at the top
+func (a *App) gotoLine(line int) {
+	_, col := a.previewView.GetScrollOffset()
+	line = min(line, a.preview.Lines()) - 1
+	a.revealLine(line)
+	a.previewView.ScrollTo(a.lineRow(line), col)
+}
+
+// scrollToEnd scrolls the preview to its last line. The text view could
+// scroll to the end of the window itself, but not tell the line it is at.
+func (a *App) scrollToEnd() {
+	a.setWindow(a.preview.Lines())
+	_, _, _, height := a.previewView.GetInnerRect()
+	a.previewView.ScrollTo(max(a.lineRow(a.windowEnd)-height, 0), 0)
+}
+
+// scrollToBeginning scrolls the preview to its first line
+func (a *App) scrollToBeginning() {
+	a.setWindow(0)
+	a.previewView.ScrollToBeginning()
+}
+
+// setWindow puts the lines of the preview around a line, counted from 0,
+// in the text view. Only a window of a long preview is in the view at a
+// time, so that the view does not go through all of it on every change.
+func (a *App) setWindow(line int) {
+	count := a.preview.Lines()
+	a.windowStart = max(min(line-previewWindowLines/2, count-previewWindowLines), 0)
+	a.windowEnd = min(a.windowStart+previewWindowLines, count)
+	a.lineRows = nil
+	if count > 0 {
+		a.previewView.SetText(a.preview.text(a.windowStart, a.windowEnd))
+	}
+}
+
+// revealLine moves the window to a line when the line is outside of it or
+// near an end of it that is not an end of the preview
+func (a *App) revealLine(line int) {
+	if (a.windowStart > 0 && line < a.windowStart+previewWindowMargin) ||
+		(a.windowEnd < a.preview.Lines() && line >= a.windowEnd-previewWindowMargin) {
+		a.setWindow(line)
+	}
+}
+
+// slideWindow runs before every draw. It moves the window once the view
+// has been scrolled near one of its ends, keeping the same text in view.
+func (a *App) slideWindow() {
+	if a.windowStart == 0 && a.windowEnd >= a.preview.Lines() {
+		return
+	}
+	row, col := a.previewView.GetScrollOffset()
+	line, lineRow := a.rowLine(row)
+	start := a.windowStart
+	a.revealLine(line)
+	if a.windowStart != start {
+		a.previewView.ScrollTo(a.lineRow(line)+lineRow, col)
+	}
+}
+
+// lineRow returns the row of the text view a line of the preview, counted
+// from 0, starts at. Lines outside the window are taken to be at its ends.
+func (a *App) lineRow(line int) int {
+	line = min(max(line, a.windowStart), a.windowEnd) - a.windowStart
+	if rows := a.windowRows(); rows != nil {
+		return rows[line]
+	}
+	return line
+}
+
+// rowLine returns the line of the preview at a row of the text view, and
+// which row of the line it is
+func (a *App) rowLine(row int) (int, int) {
+	rows := a.windowRows()
+	if rows == nil {
+		return a.windowStart + row, 0
+	}
+	i := sort.Search(len(rows), func(i int) bool { return rows[i] > row }) - 1
+	i = max(min(i, len(rows)-2), 0)
+	return a.windowStart + i, row - rows[i]
+}
+
+// windowRows returns the row every line of the window starts at, followed
+// by the number of rows of the window, or nil when lines do not wrap. The
+// rows are counted at the width of the view, once for every window and
+// width.
+func (a *App) windowRows() []int {
+	_, _, width, _ := a.previewView.GetInnerRect()
+	if !a.wrapped() || width <= 0 {
+		return nil
+	}
+
+	if a.lineRows == nil || a.rowsWidth != width {
+		a.lineRows = make([]int, 0, a.windowEnd-a.windowStart+1)
+		row := 0
+		for line := a.windowStart; line < a.windowEnd; line++ {
+			a.lineRows = append(a.lineRows, row)
+			text := a.preview.plainLine(line)
+			// Only tabs take more columns than bytes
+			if len(text) <= width && !strings.Contains(text, "\t") {
+				row++
+				continue
+			}
+			row += max(len(tview.WordWrap(tview.Escape(text), width)), 1)
+		}
+		a.lineRows = append(a.lineRows, row)
+		a.rowsWidth = width
+	}
+	return a.lineRows
+}
+
+// wrapped reports whether the preview wraps long lines
+func (a *App) wrapped() bool {
+	a.state.mu.RLock()
+	defer a.state.mu.RUnlock()
+	return a.state.wrap
+}
+
+// toggleWrap wraps the long lines of the preview, or lets them run off the
+// side to be scrolled to
+func (a *App) toggleWrap() {
+	// Keep the line at the top in place. Nothing is left off the side to
+	// scroll to once lines wrap.
+	row, col := a.previewView.GetScrollOffset()
+	line, _ := a.rowLine(row)
+
+	a.state.mu.Lock()
+	a.state.wrap = !a.state.wrap
+	wrap := a.state.wrap
+	a.state.mu.Unlock()
+
+	a.previewView.SetWrap(wrap)
+	if wrap {
+		col = 0
+	}
+	a.previewView.ScrollTo(a.lineRow(line), col)
+}
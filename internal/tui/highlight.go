@@ -1,63 +1,421 @@
 package tui
 
 import (
-	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
-	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/lexers"
 	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/dvd/cliptui/internal/language"
+	"github.com/dvd/cliptui/internal/theme"
 	"github.com/dvd/cliptui/pkg/types"
+	"github.com/rivo/tview"
+	"github.com/rivo/uniseg"
 )
 
-// HighlightContent applies syntax highlighting to content based on type
-func HighlightContent(content string, itemType string) string {
-	if itemType != types.TypeCode {
-		return content
+// typeLexers names the chroma lexer of item types with a fixed syntax. Code
+// is highlighted in its stored language.
+var typeLexers = map[string]string{
+	types.TypeJSON:  "json",
+	types.TypeYAML:  "yaml",
+	types.TypeXML:   "xml",
+	types.TypeHTML:  "html",
+	types.TypeShell: "bash",
+	types.TypeDiff:  "diff",
+}
+
+// span is a piece of text drawn in a single style
+type span struct {
+	text  string
+	style string // tview style tag such as "[red::b]", empty for the default style
+}
+
+// Preview is an item formatted for the preview view
+type Preview struct {
+	// Text has tview style tags, and region tags around search matches
+	Text string
+	// Plain is the text as it is drawn, without tags
+	Plain string
+	// View is "rendered" or "raw" for types with a rendered view, and
+	// empty otherwise
+	View string
+	// Matches are the search matches, in the order of their regions
+	Matches []Match
+	// textLines and plainLines are the offsets in Text and Plain that every
+	// line starts at
+	textLines, plainLines []int
+}
+
+// Lines returns the number of lines of the preview
+func (p Preview) Lines() int {
+	return len(p.textLines)
+}
+
+// text returns the tagged text of the lines from up to to, without the
+// last newline
+func (p Preview) text(from, to int) string {
+	end := len(p.Text)
+	if to < len(p.textLines) {
+		end = p.textLines[to] - 1
 	}
+	return p.Text[p.textLines[from]:end]
+}
 
-	lexer := lexers.Analyse(content)
-	if lexer == nil {
-		lexer = lexers.Fallback
+// plainLine returns a line as it is drawn
+func (p Preview) plainLine(line int) string {
+	end := len(p.Plain)
+	if line+1 < len(p.plainLines) {
+		end = p.plainLines[line+1] - 1
 	}
+	return p.Plain[p.plainLines[line]:end]
+}
 
-	style := styles.Get("monokai")
-	if style == nil {
-		style = styles.Fallback
+// Match is where a search match starts in a formatted preview
+type Match struct {
+	Line   int // counted from 0
+	Column int // in screen cells
+}
+
+// mark is drawn in place of an invisible character
+type mark struct {
+	text string
+	size int // bytes replaced
+}
+
+// HighlightContent applies syntax highlighting to content based on type and,
+// for code, language, returning text with tview color tags. Invisible
+// characters are replaced by markers. An empty chroma style disables
+// highlighting.
+func HighlightContent(content, itemType, lang string, th *theme.Theme) string {
+	return renderSpans(highlightSpans(content, itemType, lang, chromaStyle(th)), th, PreviewOptions{}).Text
+}
+
+// renderSpans turns spans into text with tview color tags, drawing markers
+// in place of invisible characters. With opts.LineNumbers set every line
+// starts with its number, and a positive opts.MaxLines cuts the text after
+// that many lines. The matches of opts.Search are drawn in the match color
+// as the regions "m0", "m1" and so on.
+func renderSpans(spans []span, th *theme.Theme, opts PreviewOptions) Preview {
+	var joined strings.Builder
+	for _, sp := range spans {
+		joined.WriteString(sp.text)
+	}
+	text := joined.String()
+	marks := invisibles(text)
+	markStyle := fmt.Sprintf("[%s::d]", th.Muted.Tag())
+	matchStyle := fmt.Sprintf("[%s::b]", th.Match.Tag())
+
+	// Every match opens a region at its start and closes it at its end
+	var bounds []int
+	if pattern := searchPattern(opts.Search); pattern != nil {
+		for _, m := range pattern.FindAllStringIndex(text, -1) {
+			bounds = append(bounds, m[0], m[1])
+		}
 	}
 
-	formatter := formatters.Get("terminal256")
-	if formatter == nil {
-		formatter = formatters.Fallback
+	lines := strings.Count(text, "\n") + 1
+	if opts.MaxLines > 0 && lines > opts.MaxLines {
+		lines = opts.MaxLines
+	}
+	width := len(strconv.Itoa(lines))
+
+	var buf, plain strings.Builder
+	preview := Preview{textLines: []int{0}, plainLines: []int{0}}
+	next := 0 // index of the next bound
+	line := 1
+	// column is the width of the current line up to the byte of plain at
+	// columnEnd
+	column, columnEnd := 0, 0
+	write := func(text, style string) {
+		if text == "" {
+			return
+		}
+		plain.WriteString(text)
+		if next%2 == 1 {
+			style = matchStyle
+		}
+		if style == "" {
+			buf.WriteString(tview.Escape(text))
+			return
+		}
+		fmt.Fprintf(&buf, "%s%s[-::-]", style, tview.Escape(text))
+	}
+	bound := func() {
+		if next%2 == 0 {
+			column += uniseg.StringWidth(plain.String()[columnEnd:])
+			columnEnd = plain.Len()
+			preview.Matches = append(preview.Matches, Match{Line: line - 1, Column: column})
+			fmt.Fprintf(&buf, `["m%d"]`, next/2)
+		} else {
+			buf.WriteString(`[""]`)
+		}
+		next++
+	}
+	newline := func() {
+		buf.WriteByte('\n')
+		plain.WriteByte('\n')
+		column, columnEnd = 0, plain.Len()
+		preview.textLines = append(preview.textLines, buf.Len())
+		preview.plainLines = append(preview.plainLines, plain.Len())
+	}
+	// end closes an open match and returns the preview
+	end := func() Preview {
+		if next%2 == 1 {
+			bound()
+		}
+		preview.Text, preview.Plain = buf.String(), plain.String()
+		return preview
+	}
+	gutter := func() {
+		if opts.LineNumbers {
+			number := fmt.Sprintf("%*d │", width, line)
+			fmt.Fprintf(&buf, "%s%s[-::-] ", markStyle, number)
+			plain.WriteString(number + " ")
+		}
+	}
+
+	gutter()
+	offset := 0
+	for _, sp := range spans {
+		// Styles are written per line, so that the gutter does not end them
+		segment := 0
+		for i := 0; i < len(sp.text); {
+			if next < len(bounds) && bounds[next] <= offset+i {
+				write(sp.text[segment:i], sp.style)
+				bound()
+				segment = i
+				continue
+			}
+			if sp.text[i] == '\n' {
+				write(sp.text[segment:i], sp.style)
+				if line == lines {
+					if offset+i+1 < len(text) {
+						if next%2 == 1 {
+							bound()
+						}
+						newline()
+						write("...", markStyle)
+					}
+					return end()
+				}
+				// A match across lines is closed at the end of every line and
+				// opened again after the gutter, so that lines stand on their own
+				open := next%2 == 1
+				if open {
+					buf.WriteString(`[""]`)
+				}
+				newline()
+				line++
+				gutter()
+				if open {
+					fmt.Fprintf(&buf, `["m%d"]`, next/2)
+				}
+				i++
+				segment = i
+				continue
+			}
+			m, ok := marks[offset+i]
+			if !ok {
+				i++
+				continue
+			}
+			write(sp.text[segment:i], sp.style)
+			write(m.text, markStyle)
+			i = min(i+m.size, len(sp.text))
+			segment = i
+		}
+		write(sp.text[segment:], sp.style)
+		offset += len(sp.text)
+	}
+	return end()
+}
+
+// searchPattern compiles a search of the preview for query, which ignores
+// case unless query has capitals. It returns nil for an empty query.
+func searchPattern(query string) *regexp.Regexp {
+	if query == "" {
+		return nil
+	}
+	pattern := regexp.QuoteMeta(query)
+	if strings.ToLower(query) == query {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.MustCompile(pattern)
+}
+
+// chromaStyle returns the syntax highlighting style of a theme, or nil when
+// highlighting is disabled
+func chromaStyle(th *theme.Theme) *chroma.Style {
+	if th.ChromaStyle == "" {
+		return nil
+	}
+	if style := styles.Get(th.ChromaStyle); style != nil {
+		return style
+	}
+	return styles.Fallback
+}
+
+// highlightSpans splits content into tokens styled by chroma, or returns it
+// as a single unstyled span when it is not highlighted
+func highlightSpans(content, itemType, lang string, style *chroma.Style) []span {
+	var lexer chroma.Lexer
+	if name, ok := typeLexers[itemType]; ok {
+		lexer = lexers.Get(name)
+	} else if itemType == types.TypeCode {
+		lexer = language.Lexer(lang)
+		if lexer == nil {
+			// Items stored before languages were detected
+			lexer = lexers.Analyse(content)
+		}
+		if lexer == nil {
+			lexer = lexers.Fallback
+		}
+	}
+	return lexerSpans(content, lexer, style)
+}
+
+// lexerSpans splits content into the tokens of lexer, styled by chroma. It
+// returns content as a single unstyled span without a lexer or style.
+func lexerSpans(content string, lexer chroma.Lexer, style *chroma.Style) []span {
+	plain := []span{{text: content}}
+	if lexer == nil || style == nil {
+		return plain
 	}
 
 	iterator, err := lexer.Tokenise(nil, content)
 	if err != nil {
-		return content
+		return plain
 	}
 
-	var buf bytes.Buffer
-	err = formatter.Format(&buf, style, iterator)
-	if err != nil {
-		return content
+	var spans []span
+	for token := iterator(); token != chroma.EOF; token = iterator() {
+		spans = append(spans, tokenSpan(style, token.Type, token.Value))
 	}
+	return spans
+}
 
-	return buf.String()
+// tokenSpan styles text as a chroma token. Backgrounds are left to the
+// terminal.
+func tokenSpan(style *chroma.Style, tokenType chroma.TokenType, text string) span {
+	if style == nil {
+		return span{text: text}
+	}
+	entry := style.Get(tokenType)
+
+	var attrs string
+	if entry.Bold == chroma.Yes {
+		attrs += "b"
+	}
+	if entry.Italic == chroma.Yes {
+		attrs += "i"
+	}
+	if entry.Underline == chroma.Yes {
+		attrs += "u"
+	}
+	if !entry.Colour.IsSet() && attrs == "" {
+		return span{text: text}
+	}
+
+	fg := "-"
+	if entry.Colour.IsSet() {
+		fg = entry.Colour.String()
+	}
+	return span{text: text, style: fmt.Sprintf("[%s::%s]", fg, attrs)}
 }
 
-// FormatPreview formats the preview with line numbers and highlighting
-func FormatPreview(content string, itemType string, maxLines int) string {
-	lines := strings.Split(content, "\n")
+// invisibles finds the characters of text that cannot be seen and maps their
+// byte offsets to the marker drawn in their place: "·" and "→" for spaces and
+// tabs at the end of a line, "⍽" for non-breaking spaces and the code point,
+// like <200b>, for zero-width and other format characters
+func invisibles(text string) map[int]mark {
+	marks := make(map[int]mark)
 
-	if len(lines) > maxLines {
-		lines = lines[:maxLines]
-		lines = append(lines, "...")
+	offset := 0
+	state := -1
+	for rest := text; rest != ""; {
+		var cluster string
+		cluster, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		for i, r := range cluster {
+			switch {
+			case r == '\u00a0' || r == '\u202f' || r == '\u2007':
+				marks[offset+i] = mark{text: "⍽", size: utf8.RuneLen(r)}
+			case r == '\u200d' && i+utf8.RuneLen(r) < len(cluster):
+				// Joins the emoji of a sequence like 👩‍💻
+			case r >= '\U000e0000' && r <= '\U000e007f':
+				// Tags of flag emoji
+			case unicode.Is(unicode.Cf, r):
+				marks[offset+i] = mark{text: fmt.Sprintf("<%04x>", r), size: utf8.RuneLen(r)}
+			}
+		}
+		offset += len(cluster)
 	}
 
-	if itemType == types.TypeCode {
-		highlighted := HighlightContent(strings.Join(lines, "\n"), itemType)
-		return highlighted
+	// Walk back from every line end over trailing blanks
+	for end := 0; end <= len(text); end++ {
+		if end < len(text) && text[end] != '\n' {
+			continue
+		}
+		i := end
+		if i > 0 && text[i-1] == '\r' {
+			i--
+		}
+		for i > 0 && (text[i-1] == ' ' || text[i-1] == '\t') {
+			i--
+			if text[i] == ' ' {
+				marks[i] = mark{text: "·", size: 1}
+			} else {
+				marks[i] = mark{text: "→", size: 1}
+			}
+		}
+	}
+	return marks
+}
+
+// highlightMaxSize is the size above which content is shown without syntax
+// highlighting, which would take long and make little difference
+const highlightMaxSize = 1 << 20
+
+// PreviewOptions control how FormatPreview shows an item
+type PreviewOptions struct {
+	// Raw shows the content as it is, highlighted, even when its type has a
+	// rendered view
+	Raw         bool
+	LineNumbers bool
+	// FoldDepth folds JSON objects and arrays nested this deep, 0 unfolds all
+	FoldDepth int
+	MaxLines  int
+	// Search is the text to mark matches of, case-insensitive unless it has
+	// capitals
+	Search string
+}
+
+// FormatPreview formats an item for the preview, rendered for its type
+// unless opts.Raw is set, and highlighted in the theme's chroma style
+func FormatPreview(item types.ClipboardItem, opts PreviewOptions, th *theme.Theme) Preview {
+	r := &renderer{theme: th, style: chromaStyle(th), foldDepth: opts.FoldDepth, maxLines: opts.MaxLines}
+	if len(item.Content) > highlightMaxSize {
+		r.style = nil
+	}
+	var view string
+	if hasRenderer(item.Type) {
+		if opts.Raw {
+			view = "raw"
+		} else if spans, ok := r.render(item); ok {
+			rendered := opts
+			rendered.LineNumbers = opts.LineNumbers && numbered(item.Type)
+			preview := renderSpans(spans, th, rendered)
+			preview.View = "rendered"
+			return preview
+		}
 	}
 
-	return strings.Join(lines, "\n")
+	spans := highlightSpans(types.StripEscapes(item.Content), item.Type, item.Language, r.style)
+	preview := renderSpans(spans, th, opts)
+	preview.View = view
+	return preview
 }
@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/dvd/cliptui/internal/theme"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+func TestInvisibles(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want map[int]mark
+	}{
+		{"plain", "日本語 text", map[int]mark{}},
+		{"trailing space after cjk", "日本語 \nx", map[int]mark{9: {"·", 1}}},
+		{"trailing blanks", "a \t\r\nb\t", map[int]mark{1: {"·", 1}, 2: {"→", 1}, 6: {"→", 1}}},
+		{"trailing space after combining mark", "e\u0301 ", map[int]mark{3: {"·", 1}}},
+		{"trailing space after emoji", "👩\u200d💻 ", map[int]mark{11: {"·", 1}}},
+		{"zero width space", "a\u200bb", map[int]mark{1: {"<200b>", 3}}},
+		{"zero width space in cjk", "日\u200b本", map[int]mark{3: {"<200b>", 3}}},
+		{"non-breaking spaces", "a\u00a0b\u202fc", map[int]mark{1: {"⍽", 2}, 4: {"⍽", 3}}},
+		// Joiners inside an emoji sequence are part of what is drawn
+		{"emoji sequence", "👩\u200d💻", map[int]mark{}},
+		{"dangling joiner", "x\u200d", map[int]mark{1: {"<200d>", 3}}},
+		{"flag tags", "\U0001f3f4\U000e0067\U000e0062\U000e0073\U000e0063\U000e0074\U000e007f", map[int]mark{}},
+		{"bidi control", "abc\u202edef", map[int]mark{3: {"<202e>", 3}}},
+		{"byte order mark", "\ufeffhi", map[int]mark{0: {"<feff>", 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := invisibles(tt.text)
+			if len(got) != len(tt.want) {
+				t.Fatalf("invisibles(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+			for offset, want := range tt.want {
+				if got[offset] != want {
+					t.Errorf("invisibles(%q)[%d] = %v, want %v", tt.text, offset, got[offset], want)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatPreviewMarkers(t *testing.T) {
+	th, err := theme.Load(theme.NoColor, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"日本語 \nx", "日本語·\nx"},
+		// Escape sequences are stripped before blanks are marked
+		{"\x1b[31mred\x1b[0m \t", "red·→"},
+		{"a\u200bb\u00a0c", "a<200b>b⍽c"},
+		{"e\u0301 👩\u200d💻", "e\u0301 👩\u200d💻"},
+		{"[red]tag[-] ", "[red]tag[-]·"},
+	}
+
+	for _, tt := range tests {
+		item := types.ClipboardItem{Content: tt.content, Type: types.TypeText}
+		if got := FormatPreview(item, PreviewOptions{}, th).Plain; got != tt.want {
+			t.Errorf("FormatPreview(%q).Plain = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestPreviewLines(t *testing.T) {
+	th, err := theme.Load(theme.NoColor, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := types.ClipboardItem{Content: "one\ntwo words\n\nfour", Type: types.TypeText}
+	preview := FormatPreview(item, PreviewOptions{LineNumbers: true, Search: "ne\ntw"}, th)
+	if got := preview.Lines(); got != 4 {
+		t.Fatalf("Lines() = %d, want 4", got)
+	}
+	for i, want := range []string{"1 │ one", "2 │ two words", "3 │ ", "4 │ four"} {
+		if got := preview.plainLine(i); got != want {
+			t.Errorf("plainLine(%d) = %q, want %q", i, got, want)
+		}
+	}
+	if got := preview.text(0, 4); got != preview.Text {
+		t.Errorf("text(0, 4) = %q, want all of %q", got, preview.Text)
+	}
+
+	// The match across the first two lines is a region on each of them
+	for i := range 2 {
+		tags := regionTags(preview.text(i, i+1))
+		if !slices.Equal(tags, []string{`["m0"]`, `[""]`}) {
+			t.Errorf("line %d has the regions %v", i, tags)
+		}
+	}
+	if tags := regionTags(preview.text(2, 4)); len(tags) != 0 {
+		t.Errorf("lines 2 and 3 have the regions %v", tags)
+	}
+}
+
+// regionTags returns the region tags of tagged text in order
+func regionTags(text string) []string {
+	return regexp.MustCompile(`\["[^"]*"\]`).FindAllString(text, -1)
+}
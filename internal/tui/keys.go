@@ -0,0 +1,190 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/dvd/cliptui/internal/keymap"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// buildKeymaps creates the keymap of every mode from the configured bindings
+func (a *App) buildKeymaps() error {
+	a.keymaps = make(map[string]*keymap.Keymap)
+	for _, mode := range keymap.Modes() {
+		km, err := keymap.New(mode, a.config.Keybindings[mode])
+		if err != nil {
+			return err
+		}
+		a.keymaps[mode] = km
+	}
+	return nil
+}
+
+// helpText returns the help bar for a mode, generated from its keymap
+func (a *App) helpText(mode string) string {
+	return fmt.Sprintf("  [%s]%s[-]", a.theme.HelpText.Tag(), tview.Escape(a.keymaps[mode].Help()))
+}
+
+// handleListKey runs the list action bound to a key press. Keys without a
+// binding are swallowed so the table cannot move the selection on its own.
+func (a *App) handleListKey(event *tcell.EventKey) *tcell.EventKey {
+	key := keymap.FromEvent(event)
+	action, _ := a.keymaps[keymap.ModeList].Feed(key)
+
+	switch action {
+	case "quick_copy":
+		a.handleQuickCopyAction(key)
+	case "up":
+		a.moveCursorUp()
+	case "down":
+		a.moveCursorDown()
+	case "top":
+		a.moveCursorTo(0)
+	case "bottom":
+		a.moveCursorTo(a.listWidget.GetRowCount() - 2)
+	case "copy":
+		a.handleCopyAction(false)
+	case "copy_quit":
+		a.handleCopyAction(true)
+	case "preview":
+		a.switchToPreviewMode()
+	case "toggle_preview":
+		a.togglePreviewPane()
+	case "search":
+		a.switchToSearchMode()
+	case "mark":
+		a.toggleMark()
+	case "visual":
+		a.toggleVisualMode()
+	case "invert_selection":
+		a.invertSelection()
+	case "clear_selection":
+		a.clearSelection()
+	case "pin":
+		a.handlePinAction()
+	case "tag":
+		a.switchToPrompt(promptTag)
+	case "export":
+		a.switchToPrompt(promptExport)
+	case "join":
+		a.switchToPrompt(promptJoin)
+	case "delete":
+		a.handleDeleteAction()
+	case "clear":
+		a.handleClearAllAction()
+	case "toggle_capture":
+		a.handleTogglePauseAction()
+	case "toggle_stay_open":
+		a.toggleStayOpen()
+	case "quit":
+		a.app.Stop()
+	}
+	return nil
+}
+
+// handlePreviewKey runs the preview action bound to a key press
+func (a *App) handlePreviewKey(event *tcell.EventKey) *tcell.EventKey {
+	action, consumed := a.keymaps[keymap.ModePreview].Feed(keymap.FromEvent(event))
+
+	switch action {
+	case "copy":
+		a.handleCopyAction(false)
+	case "copy_quit":
+		a.handleCopyAction(true)
+	case "scroll_up":
+		row, col := a.previewView.GetScrollOffset()
+		if row > 0 {
+			a.previewView.ScrollTo(row-1, col)
+		}
+	case "scroll_down":
+		row, col := a.previewView.GetScrollOffset()
+		a.previewView.ScrollTo(row+1, col)
+	case "top":
+		a.scrollToBeginning()
+	case "bottom":
+		a.scrollToEnd()
+	case "language":
+		a.switchToLanguageInput()
+	case "toggle_view":
+		a.togglePreviewView()
+	case "line_numbers":
+		a.toggleLineNumbers()
+	case "fold":
+		a.foldPreview(-1)
+	case "unfold":
+		a.foldPreview(1)
+	case "search":
+		a.switchToPreviewPrompt(promptFind)
+	case "next_match":
+		a.showMatch(1)
+	case "prev_match":
+		a.showMatch(-1)
+	case "toggle_wrap":
+		a.toggleWrap()
+	case "goto_line":
+		a.switchToPreviewPrompt(promptLine)
+	case "back":
+		a.switchToListMode()
+	default:
+		if !consumed {
+			// Let the text view handle page up/down and horizontal scrolling
+			return event
+		}
+	}
+	return nil
+}
+
+// handleSearchKey runs the search action bound to a key press and passes
+// everything else on to the input field
+func (a *App) handleSearchKey(event *tcell.EventKey) *tcell.EventKey {
+	action, consumed := a.keymaps[keymap.ModeSearch].Feed(keymap.FromEvent(event))
+
+	switch action {
+	case "confirm":
+		a.exitSearchMode()
+	case "cancel":
+		a.state.mu.Lock()
+		a.state.searchQuery = ""
+		a.filterItems()
+		a.state.cursor = 0
+		a.state.mu.Unlock()
+		a.exitSearchMode()
+		a.updateListDisplay()
+	case "toggle_mode":
+		a.state.mu.Lock()
+		a.state.searchMode = a.state.searchMode.Next()
+		a.filterItems()
+		a.state.cursor = 0
+		a.state.mu.Unlock()
+		a.updateSearchTitle()
+		a.updateListDisplay()
+	default:
+		if !consumed {
+			return event
+		}
+	}
+	return nil
+}
+
+// handleQuickCopyAction copies the item numbered by the quick copy key that
+// was pressed (the first key copies the first item, and so on)
+func (a *App) handleQuickCopyAction(key keymap.Key) {
+	num := -1
+	for i, seq := range a.keymaps[keymap.ModeList].Keys("quick_copy") {
+		if seq[len(seq)-1].Matches(key) {
+			num = i
+			break
+		}
+	}
+
+	a.state.mu.RLock()
+	if num < 0 || num >= len(a.state.filteredItems) {
+		a.state.mu.RUnlock()
+		return
+	}
+	item := a.state.filteredItems[num]
+	a.state.mu.RUnlock()
+
+	a.copyItem(item, false)
+}
@@ -1,8 +1,11 @@
 package tui
 
 import (
-	"github.com/dvd/cliptui/internal/clipboard"
-	"github.com/dvd/cliptui/internal/search"
+	"fmt"
+	"strings"
+
+	"github.com/dvd/cliptui/internal/keymap"
+	"github.com/dvd/cliptui/internal/language"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
@@ -14,6 +17,7 @@ func (a *App) moveCursorUp() {
 		a.listWidget.Select(row-1, 1)
 		a.state.mu.Lock()
 		a.state.cursor = row - 2
+		a.extendVisual()
 		a.state.mu.Unlock()
 		a.updateListDisplay()
 	}
@@ -26,21 +30,39 @@ func (a *App) moveCursorDown() {
 		a.listWidget.Select(row+1, 1)
 		a.state.mu.Lock()
 		a.state.cursor = row
+		a.extendVisual()
 		a.state.mu.Unlock()
 		a.updateListDisplay()
 	}
 }
 
+// moveCursorTo selects the item at index, clamped to the list
+func (a *App) moveCursorTo(index int) {
+	count := a.listWidget.GetRowCount() - 1
+	if count <= 0 {
+		return
+	}
+	if index >= count {
+		index = count - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	a.listWidget.Select(index+1, 1)
+	a.state.mu.Lock()
+	a.state.cursor = index
+	a.extendVisual()
+	a.state.mu.Unlock()
+	a.updateListDisplay()
+}
+
 // buildListPage creates the list mode layout
 func (a *App) buildListPage() tview.Primitive {
 	// Table widget - use terminal default colors
 	a.listWidget = tview.NewTable().
 		SetFixed(1, 0). // Fix the header row
 		SetSelectable(true, false).
-		SetSelectedStyle(tcell.StyleDefault.
-			Background(tcell.ColorDefault).
-			Foreground(tcell.ColorDefault).
-			Reverse(true)). // Use reverse video for selection
+		SetSelectedStyle(a.theme.SelectionStyle()).
 		SetSeparator(' ')
 	a.listWidget.SetBorder(false)
 
@@ -56,77 +78,24 @@ func (a *App) buildListPage() tview.Primitive {
 		return action, event
 	})
 
-	a.listWidget.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Rune() {
-		case 'j':
-			a.moveCursorDown()
-			return nil
-		case 'k':
-			a.moveCursorUp()
-			return nil
-		case 'p':
-			a.switchToPreviewMode()
-			return nil
-		case '/':
-			a.switchToSearchMode()
-			return nil
-		case 'd':
-			a.handleDeleteAction()
-			return nil
-		case 'D':
-			a.handleClearAllAction()
-			return nil
-		case 'y':
-			a.handleCopyAction()
-			return nil
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			// Quick copy by number (0 = first item, 9 = tenth item)
-			num := int(event.Rune() - '0')
-			a.state.mu.RLock()
-			if num < len(a.state.filteredItems) {
-				item := a.state.filteredItems[num]
-				a.state.mu.RUnlock()
-				clipboard.SetClipboard(item.Content)
-				a.app.Stop()
-			} else {
-				a.state.mu.RUnlock()
-			}
-			return nil
-		}
-
-		if event.Key() == tcell.KeyEnter {
-			a.handleCopyAction()
-			return nil
-		}
-
-		if event.Key() == tcell.KeyDown {
-			a.moveCursorDown()
-			return nil
-		}
-		if event.Key() == tcell.KeyUp {
-			a.moveCursorUp()
-			return nil
-		}
-
-		return event
-	})
+	a.listWidget.SetInputCapture(a.handleListKey)
 
 	a.listHelp = tview.NewTextView().
 		SetDynamicColors(true).
 		SetTextAlign(tview.AlignLeft)
-	a.listHelp.SetText("  0-9 quick copy • ↑/k up • ↓/j down • enter/y copy • p preview • / search • d delete • D clear • q quit")
+	a.listHelp.SetText(a.helpText(keymap.ModeList))
 	a.listHelp.SetBorder(true).
 		SetTitle(" Shortcuts ").
 		SetTitleAlign(tview.AlignLeft).
-		SetBorderColor(tcell.ColorBlue).
-		SetTitleColor(tcell.ColorBlue)
+		SetBorderColor(a.theme.HelpBorder.Color).
+		SetTitleColor(a.theme.HelpBorder.Color)
 
 	a.listContainer = tview.NewFlex().
 		SetDirection(tview.FlexRow).
 		AddItem(a.listWidget, 0, 1, true)
 	a.listContainer.SetBorder(true).
-		SetBorderColor(tcell.ColorGreen).
-		SetTitleColor(tcell.ColorGreen).
+		SetBorderColor(a.theme.ListBorder.Color).
+		SetTitleColor(a.theme.ListBorder.Color).
 		SetBorderPadding(0, 0, 1, 1).
 		SetTitle(" Clipboard History ").
 		SetTitleAlign(tview.AlignLeft)
@@ -137,50 +106,52 @@ func (a *App) buildListPage() tview.Primitive {
 		SetLabel("").
 		SetPlaceholder("Type to search...").
 		SetFieldBackgroundColor(tcell.ColorDefault).
-		SetFieldTextColor(tcell.ColorWhite).
-		SetPlaceholderTextColor(tcell.ColorGray)
+		SetFieldTextColor(a.theme.SearchText.Color).
+		SetPlaceholderTextColor(a.theme.Placeholder.Color)
 
 	a.searchInput.SetBorder(true).
-		SetBorderColor(tcell.ColorYellow).
-		SetTitleColor(tcell.ColorYellow).
-		SetTitle(" Search (ESC to cancel, Enter to confirm) ").
+		SetBorderColor(a.theme.SearchBorder.Color).
+		SetTitleColor(a.theme.SearchBorder.Color).
 		SetTitleAlign(tview.AlignLeft).
 		SetBorderPadding(0, 0, 1, 1)
 
 	a.searchInput.SetChangedFunc(func(text string) {
 		a.state.mu.Lock()
 		a.state.searchQuery = text
-		if text == "" {
-			a.state.filteredItems = a.state.items
-		} else {
-			a.state.filteredItems = search.Filter(a.state.items, text)
-		}
+		a.filterItems()
 		a.state.cursor = 0
 		a.state.mu.Unlock()
 		a.updateListDisplay()
 	})
 
-	a.searchInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyEscape {
-			a.state.mu.Lock()
-			a.state.filteredItems = a.state.items
-			a.state.searchQuery = ""
-			a.state.cursor = 0
-			a.state.mu.Unlock()
-			a.exitSearchMode()
-			a.updateListDisplay()
-			return nil
-		}
-		if event.Key() == tcell.KeyEnter {
-			a.exitSearchMode()
-			return nil
-		}
-		return event
-	})
+	a.searchInput.SetInputCapture(a.handleSearchKey)
+
+	a.searchError = tview.NewTextView().
+		SetTextColor(a.theme.Error.Color)
+	a.searchError.SetBorderPadding(0, 0, 2, 2)
+	a.updateSearchTitle()
+
+	a.promptInput = tview.NewInputField().
+		SetLabel("").
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetFieldTextColor(a.theme.SearchText.Color).
+		SetPlaceholderTextColor(a.theme.Placeholder.Color).
+		SetAutocompleteUseTags(false).
+		SetDoneFunc(a.handlePromptDone)
+	a.promptInput.SetBorder(true).
+		SetBorderColor(a.theme.SearchBorder.Color).
+		SetTitleColor(a.theme.SearchBorder.Color).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderPadding(0, 0, 1, 1)
+
+	a.splitFlex = tview.NewFlex().
+		AddItem(a.listContainer, 0, 1, true).
+		AddItem(a.previewView, 0, 0, false)
 
 	a.mainFlex = tview.NewFlex().
 		SetDirection(tview.FlexRow).
-		AddItem(a.listContainer, 0, 1, true).
+		AddItem(a.splitFlex, 0, 1, true).
+		AddItem(a.statusLine, 0, 0, false).
 		AddItem(a.listHelp, searchInputHeight, 0, false)
 
 	outer := tview.NewFlex().
@@ -196,6 +167,16 @@ func (a *App) buildListPage() tview.Primitive {
 	return outer
 }
 
+// updateSearchTitle shows the current search mode in the search box title
+func (a *App) updateSearchTitle() {
+	a.state.mu.RLock()
+	searchMode := a.state.searchMode
+	a.state.mu.RUnlock()
+
+	a.searchInput.SetTitle(tview.Escape(fmt.Sprintf(" Search [%s] (%s) ",
+		searchMode, a.keymaps[keymap.ModeSearch].Help())))
+}
+
 // buildPreviewPage creates the preview mode layout
 func (a *App) buildPreviewPage() tview.Primitive {
 	a.previewHeader = tview.NewTextView()
@@ -203,41 +184,60 @@ func (a *App) buildPreviewPage() tview.Primitive {
 	a.previewView = tview.NewTextView().
 		SetDynamicColors(true).
 		SetScrollable(true).
+		SetWrap(a.state.wrap).
 		SetWordWrap(true).
-		SetTextColor(tcell.ColorDefault)
+		SetRegions(true).
+		SetTextColor(a.theme.Text.Color)
 	a.previewView.SetBorder(true).
-		SetBorderColor(tcell.ColorGreen).
-		SetTitleColor(tcell.ColorGreen).
+		SetBorderColor(a.theme.PreviewBorder.Color).
+		SetTitleColor(a.theme.PreviewBorder.Color).
 		SetBorderPadding(1, 0, 1, 1).
 		SetTitle(" Preview ").
 		SetTitleAlign(tview.AlignLeft)
 
-	a.previewView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
-			a.switchToListMode()
-			return nil
-		}
-		if event.Key() == tcell.KeyEnter || event.Rune() == 'y' {
-			a.handleCopyAction()
-			return nil
-		}
-		return event
-	})
+	a.previewView.SetInputCapture(a.handlePreviewKey)
 
 	a.previewHelp = tview.NewTextView().
 		SetDynamicColors(true).
 		SetTextAlign(tview.AlignLeft)
-	a.previewHelp.SetText("  enter/y copy • esc/q back • ↑↓ scroll")
+	a.previewHelp.SetText(a.helpText(keymap.ModePreview))
 	a.previewHelp.SetBorder(true).
 		SetTitle(" Shortcuts ").
 		SetTitleAlign(tview.AlignLeft).
-		SetBorderColor(tcell.ColorBlue).
-		SetTitleColor(tcell.ColorBlue).
+		SetBorderColor(a.theme.HelpBorder.Color).
+		SetTitleColor(a.theme.HelpBorder.Color).
+		SetBorderPadding(0, 0, 1, 1)
+
+	a.languageInput = tview.NewInputField().
+		SetLabel("").
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetFieldTextColor(a.theme.SearchText.Color).
+		SetPlaceholderTextColor(a.theme.Placeholder.Color).
+		SetAutocompleteUseTags(false).
+		SetAutocompleteFunc(completeLanguage).
+		SetDoneFunc(a.handleLanguageDone)
+	a.languageInput.SetBorder(true).
+		SetBorderColor(a.theme.SearchBorder.Color).
+		SetTitleColor(a.theme.SearchBorder.Color).
+		SetTitleAlign(tview.AlignLeft).
 		SetBorderPadding(0, 0, 1, 1)
 
-	flex := tview.NewFlex().
+	a.previewInput = tview.NewInputField().
+		SetLabel("").
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetFieldTextColor(a.theme.SearchText.Color).
+		SetPlaceholderTextColor(a.theme.Placeholder.Color).
+		SetDoneFunc(a.handlePreviewPromptDone)
+	a.previewInput.SetBorder(true).
+		SetBorderColor(a.theme.SearchBorder.Color).
+		SetTitleColor(a.theme.SearchBorder.Color).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderPadding(0, 0, 1, 1)
+
+	a.previewFlex = tview.NewFlex().
 		SetDirection(tview.FlexRow).
 		AddItem(a.previewView, 0, 1, true).
+		AddItem(a.statusLine, 0, 0, false).
 		AddItem(a.previewHelp, searchInputHeight, 0, false)
 
 	outer := tview.NewFlex().
@@ -245,10 +245,25 @@ func (a *App) buildPreviewPage() tview.Primitive {
 		AddItem(nil, 1, 0, false).
 		AddItem(tview.NewFlex().
 			AddItem(nil, 2, 0, false).
-			AddItem(flex, 0, 1, true).
+			AddItem(a.previewFlex, 0, 1, true).
 			AddItem(nil, 2, 0, false),
 			0, 1, true).
 		AddItem(nil, 1, 0, false)
 
 	return outer
 }
+
+// completeLanguage lists the languages starting with the typed text
+func completeLanguage(text string) []string {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return nil
+	}
+	var entries []string
+	for _, name := range language.Names() {
+		if strings.HasPrefix(strings.ToLower(name), text) {
+			entries = append(entries, name)
+		}
+	}
+	return entries
+}
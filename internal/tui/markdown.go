@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/dvd/cliptui/internal/language"
+)
+
+// ruleWidth is the width of a rendered horizontal rule
+const ruleWidth = 40
+
+var (
+	mdFence   = regexp.MustCompile("^\\s*(```|~~~)\\s*([\\w+#.-]*)")
+	mdHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdRule    = regexp.MustCompile(`^\s*([-*_])(\s*([-*_]))*\s*$`)
+	mdQuote   = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	mdTask    = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s+(.*)$`)
+	mdBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdOrdered = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	// mdInline matches, in order of the groups: code, images, links, bold
+	// and italic text
+	mdInline = regexp.MustCompile("`([^`]+)`" +
+		`|!\[([^\]]*)\]\(([^)\s]*)[^)]*\)` +
+		`|\[([^\]]+)\]\(([^)\s]*)[^)]*\)` +
+		`|\*\*([^*]+)\*\*|__([^_]+)__` +
+		`|\*([^*\s][^*]*)\*|\b_([^_\s][^_]*)_\b`)
+)
+
+// markdown renders Markdown for the terminal: headings, emphasis, lists,
+// quotes and rules are styled and their markup removed, and code blocks
+// are highlighted in the language of their fence
+func (r *renderer) markdown(content string) []span {
+	var spans []span
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if i > 0 {
+			spans = append(spans, span{text: "\n"})
+		}
+
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			// Collect the block up to the closing fence or the end
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), m[1]) {
+				end++
+			}
+			spans = append(spans, r.muted(strings.Repeat("─", 2)+" "+m[2]))
+			code := strings.Join(lines[i+1:min(end, len(lines))], "\n")
+			if code != "" {
+				spans = append(spans, span{text: "\n"})
+				spans = append(spans, lexerSpans(code, language.Lexer(m[2]), r.style)...)
+			}
+			if end < len(lines) {
+				spans = append(spans, span{text: "\n"}, r.muted(strings.Repeat("─", 2)))
+			}
+			i = end
+			continue
+		}
+
+		switch {
+		case mdHeading.MatchString(line):
+			m := mdHeading.FindStringSubmatch(line)
+			style := fmt.Sprintf("[%s::b]", r.theme.Header.Tag())
+			if len(m[1]) == 1 {
+				style = fmt.Sprintf("[%s::bu]", r.theme.Header.Tag())
+			}
+			spans = append(spans, span{text: m[2], style: style})
+		case mdRule.MatchString(line) && strings.Count(line, string(strings.TrimSpace(line)[0])) >= 3:
+			spans = append(spans, r.muted(strings.Repeat("─", ruleWidth)))
+		case mdQuote.MatchString(line):
+			spans = append(spans, r.muted("│ "))
+			spans = append(spans, r.inline(mdQuote.FindStringSubmatch(line)[1])...)
+		case mdTask.MatchString(line):
+			m := mdTask.FindStringSubmatch(line)
+			box := "☐ "
+			if m[2] != " " {
+				box = "☑ "
+			}
+			spans = append(spans, span{text: m[1] + box})
+			spans = append(spans, r.inline(m[3])...)
+		case mdBullet.MatchString(line):
+			m := mdBullet.FindStringSubmatch(line)
+			spans = append(spans, span{text: m[1]}, span{text: "• ", style: fmt.Sprintf("[%s]", r.theme.Number.Tag())})
+			spans = append(spans, r.inline(m[2])...)
+		case mdOrdered.MatchString(line):
+			m := mdOrdered.FindStringSubmatch(line)
+			spans = append(spans, span{text: m[1]}, span{text: m[2] + " ", style: fmt.Sprintf("[%s]", r.theme.Number.Tag())})
+			spans = append(spans, r.inline(m[3])...)
+		default:
+			spans = append(spans, r.inline(line)...)
+		}
+	}
+	return spans
+}
+
+// inline renders the code, links, images and emphasis of a line of Markdown
+func (r *renderer) inline(text string) []span {
+	var spans []span
+	last := 0
+	for _, m := range mdInline.FindAllStringSubmatchIndex(text, -1) {
+		spans = append(spans, span{text: text[last:m[0]]})
+		last = m[1]
+
+		group := func(n int) string {
+			if m[2*n] < 0 {
+				return ""
+			}
+			return text[m[2*n]:m[2*n+1]]
+		}
+		switch {
+		case m[2] >= 0:
+			code := tokenSpan(r.style, chroma.LiteralStringBacktick, group(1))
+			if code.style == "" {
+				code.style = "[::r]"
+			}
+			spans = append(spans, code)
+		case m[4] >= 0:
+			spans = append(spans, r.muted("[image: "+group(2)+"]"))
+		case m[8] >= 0:
+			spans = append(spans, span{text: group(4), style: "[::u]"}, r.muted(" ("+group(5)+")"))
+		case m[12] >= 0 || m[14] >= 0:
+			spans = append(spans, span{text: group(6) + group(7), style: "[::b]"})
+		default:
+			spans = append(spans, span{text: group(8) + group(9), style: "[::i]"})
+		}
+	}
+	return append(spans, span{text: text[last:]})
+}
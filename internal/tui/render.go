@@ -0,0 +1,480 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/dvd/cliptui/internal/theme"
+	"github.com/dvd/cliptui/pkg/types"
+	"github.com/gdamore/tcell/v2"
+)
+
+// labelWidth aligns the values of the URL and color views
+const labelWidth = 10
+
+// swatchWidth and swatchHeight are the size of a color swatch in cells
+const (
+	swatchWidth  = 24
+	swatchHeight = 3
+)
+
+// hexDumpMaxSize is the most bytes a hex dump shows, also where every line
+// is shown
+const hexDumpMaxSize = 256 << 10
+
+// colorArgs matches the numbers of rgb() and hsl() colors
+var colorArgs = regexp.MustCompile(`[\d.]+%?`)
+
+// renderer builds the rendered views of items
+type renderer struct {
+	theme     *theme.Theme
+	style     *chroma.Style // nil when highlighting is disabled
+	foldDepth int
+	maxLines  int
+}
+
+// hasRenderer reports whether items of a type have a rendered view
+func hasRenderer(itemType string) bool {
+	switch itemType {
+	case types.TypeJSON, types.TypeMarkdown, types.TypeURL, types.TypeColor, types.TypeBinary:
+		return true
+	}
+	return false
+}
+
+// numbered reports whether the rendered view of a type is text whose lines
+// are worth numbering, unlike tables such as the URL breakdown
+func numbered(itemType string) bool {
+	return itemType == types.TypeJSON || itemType == types.TypeMarkdown
+}
+
+// render returns the rendered view of an item, or false when its content
+// cannot be rendered, like JSON with a syntax error
+func (r *renderer) render(item types.ClipboardItem) ([]span, bool) {
+	switch item.Type {
+	case types.TypeJSON:
+		return r.json(item.Content)
+	case types.TypeMarkdown:
+		return r.markdown(types.StripEscapes(item.Content)), true
+	case types.TypeURL:
+		return r.url(item.Content)
+	case types.TypeColor:
+		return r.color(item.Content)
+	case types.TypeBinary:
+		return r.hexDump(item.Content), true
+	}
+	return nil, false
+}
+
+// label styles the name of a field in the URL and color views
+func (r *renderer) label(name string) span {
+	return span{
+		text:  fmt.Sprintf("%-*s", labelWidth, name),
+		style: fmt.Sprintf("[%s::b]", r.theme.Header.Tag()),
+	}
+}
+
+// muted styles text of lesser importance
+func (r *renderer) muted(text string) span {
+	return span{text: text, style: fmt.Sprintf("[%s::d]", r.theme.Muted.Tag())}
+}
+
+// jsonNode is a parsed JSON value that keeps the order of object keys
+type jsonNode struct {
+	key      string     // key in the parent object, if any
+	delim    json.Delim // '{' or '[' for containers, 0 for scalars
+	value    any        // scalars: string, json.Number, bool or nil
+	children []jsonNode
+}
+
+// parseJSON parses a single JSON value
+func parseJSON(content string) (jsonNode, error) {
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	node, err := parseJSONValue(dec)
+	if err != nil {
+		return node, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return node, errors.New("data after the JSON value")
+	}
+	return node, nil
+}
+
+// parseJSONValue parses the value starting at the next token
+func parseJSONValue(dec *json.Decoder) (jsonNode, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return jsonNode{}, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return jsonNode{value: token}, nil
+	}
+
+	node := jsonNode{delim: delim}
+	for dec.More() {
+		var key string
+		if delim == '{' {
+			token, err := dec.Token()
+			if err != nil {
+				return node, err
+			}
+			key, _ = token.(string)
+		}
+		child, err := parseJSONValue(dec)
+		if err != nil {
+			return node, err
+		}
+		child.key = key
+		node.children = append(node.children, child)
+	}
+	// The closing delimiter
+	if _, err := dec.Token(); err != nil {
+		return node, err
+	}
+	return node, nil
+}
+
+// depth returns how deeply containers are nested in n, 1 for a flat object
+func (n jsonNode) depth() int {
+	if n.delim == 0 {
+		return 0
+	}
+	deepest := 0
+	for _, child := range n.children {
+		deepest = max(deepest, child.depth())
+	}
+	return deepest + 1
+}
+
+// jsonFoldDepth returns how deeply the containers of JSON content are
+// nested, 0 when it is not valid JSON
+func jsonFoldDepth(content string) int {
+	node, err := parseJSON(content)
+	if err != nil {
+		return 0
+	}
+	return node.depth()
+}
+
+// json pretty-prints JSON, folding the objects and arrays nested deeper
+// than the fold depth
+func (r *renderer) json(content string) ([]span, bool) {
+	node, err := parseJSON(content)
+	if err != nil {
+		return nil, false
+	}
+	var spans []span
+	r.jsonValue(&spans, node, 0, true)
+	return spans, true
+}
+
+// jsonValue appends the spans of a value at the given nesting level
+func (r *renderer) jsonValue(spans *[]span, n jsonNode, level int, last bool) {
+	punct := func(text string) {
+		*spans = append(*spans, tokenSpan(r.style, chroma.Punctuation, text))
+	}
+
+	switch {
+	case n.delim == 0:
+		switch v := n.value.(type) {
+		case string:
+			*spans = append(*spans, tokenSpan(r.style, chroma.LiteralStringDouble, quoteJSON(v)))
+		case json.Number:
+			*spans = append(*spans, tokenSpan(r.style, chroma.LiteralNumber, v.String()))
+		default:
+			*spans = append(*spans, tokenSpan(r.style, chroma.KeywordConstant, jsonLiteral(v)))
+		}
+	case len(n.children) == 0:
+		punct(string(n.delim) + closing(n.delim))
+	case r.foldDepth > 0 && level >= r.foldDepth:
+		punct(string(n.delim) + "…" + closing(n.delim))
+		noun := "item"
+		if n.delim == '{' {
+			noun = "key"
+		}
+		*spans = append(*spans, r.muted(" "+plural(len(n.children), noun)))
+	default:
+		punct(string(n.delim))
+		*spans = append(*spans, span{text: "\n"})
+		indent := strings.Repeat("  ", level+1)
+		for i, child := range n.children {
+			*spans = append(*spans, span{text: indent})
+			if n.delim == '{' {
+				*spans = append(*spans, tokenSpan(r.style, chroma.NameTag, quoteJSON(child.key)))
+				punct(": ")
+			}
+			r.jsonValue(spans, child, level+1, i == len(n.children)-1)
+			*spans = append(*spans, span{text: "\n"})
+		}
+		*spans = append(*spans, span{text: strings.Repeat("  ", level)})
+		punct(closing(n.delim))
+	}
+
+	if !last {
+		punct(",")
+	}
+}
+
+// closing returns the delimiter that closes an object or array
+func closing(delim json.Delim) string {
+	if delim == '{' {
+		return "}"
+	}
+	return "]"
+}
+
+// jsonLiteral spells out true, false and null
+func jsonLiteral(v any) string {
+	if v == nil {
+		return "null"
+	}
+	return fmt.Sprint(v)
+}
+
+// quoteJSON quotes a string as JSON without escaping HTML characters
+func quoteJSON(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// url breaks a URL into its parts, with the path and query decoded
+func (r *renderer) url(content string) ([]span, bool) {
+	u, err := url.Parse(strings.TrimSpace(content))
+	if err != nil || u.Scheme == "" {
+		return nil, false
+	}
+
+	var spans []span
+	field := func(name, value string) {
+		if value == "" {
+			return
+		}
+		spans = append(spans, r.label(name), span{text: value + "\n"})
+	}
+	field("Scheme", u.Scheme)
+	if u.User != nil {
+		field("User", u.User.Username())
+	}
+	field("Host", u.Hostname())
+	field("Port", u.Port())
+	field("Path", u.Path)
+	if u.Opaque != "" {
+		field("Opaque", u.Opaque)
+	}
+
+	if u.RawQuery != "" {
+		query := r.label("Query")
+		query.text = strings.TrimSpace(query.text) + "\n"
+		spans = append(spans, query)
+		// Split by hand, url.ParseQuery loses the order of the parameters
+		for _, param := range strings.Split(u.RawQuery, "&") {
+			if param == "" {
+				continue
+			}
+			key, value, _ := strings.Cut(param, "=")
+			key = unescapeQuery(key)
+			if value != "" {
+				key = fmt.Sprintf("%-*s ", labelWidth-3, key)
+			}
+			spans = append(spans,
+				span{text: "  "},
+				tokenSpan(r.style, chroma.NameAttribute, key),
+				span{text: unescapeQuery(value) + "\n"})
+		}
+	}
+	field("Fragment", u.Fragment)
+
+	if len(spans) > 0 {
+		last := &spans[len(spans)-1]
+		last.text = strings.TrimSuffix(last.text, "\n")
+	}
+	return spans, true
+}
+
+// unescapeQuery decodes a query parameter, leaving it as it is when it is
+// not validly escaped
+func unescapeQuery(s string) string {
+	if decoded, err := url.QueryUnescape(s); err == nil {
+		return decoded
+	}
+	return s
+}
+
+// color shows a swatch of a color, unless the theme has no colors, and its
+// value in hex, RGB and HSL
+func (r *renderer) color(content string) ([]span, bool) {
+	red, green, blue, alpha, ok := parseColor(strings.TrimSpace(content))
+	if !ok {
+		return nil, false
+	}
+	hexValue := fmt.Sprintf("#%02x%02x%02x", red, green, blue)
+
+	var spans []span
+	if !r.theme.Monochrome {
+		swatchColor := theme.Color{Color: tcell.NewRGBColor(int32(red), int32(green), int32(blue))}
+		swatch := strings.Repeat("█", swatchWidth) + "\n"
+		for range swatchHeight {
+			spans = append(spans, span{text: swatch, style: fmt.Sprintf("[%s]", swatchColor.Tag())})
+		}
+		spans = append(spans, span{text: "\n"})
+	}
+
+	h, s, l := rgbToHSL(red, green, blue)
+	spans = append(spans,
+		r.label("Hex"), span{text: hexValue + "\n"},
+		r.label("RGB"), span{text: fmt.Sprintf("rgb(%d, %d, %d)\n", red, green, blue)},
+		r.label("HSL"), span{text: fmt.Sprintf("hsl(%d, %d%%, %d%%)", h, s, l)})
+	if alpha < 1 {
+		spans = append(spans, span{text: "\n"}, r.label("Alpha"), span{text: strconv.FormatFloat(alpha, 'f', -1, 64)})
+	}
+	return spans, true
+}
+
+// parseColor reads a CSS hex, rgb() or hsl() color
+func parseColor(s string) (red, green, blue uint8, alpha float64, ok bool) {
+	alpha = 1
+	if digits, found := strings.CutPrefix(s, "#"); found {
+		if len(digits) == 3 || len(digits) == 4 {
+			var long strings.Builder
+			for _, c := range digits {
+				long.WriteRune(c)
+				long.WriteRune(c)
+			}
+			digits = long.String()
+		}
+		b, err := hex.DecodeString(digits)
+		if err != nil || (len(b) != 3 && len(b) != 4) {
+			return 0, 0, 0, 0, false
+		}
+		if len(b) == 4 {
+			alpha = float64(b[3]) / 255
+		}
+		return b[0], b[1], b[2], alpha, true
+	}
+
+	lower := strings.ToLower(s)
+	args := colorArgs.FindAllString(lower, -1)
+	if len(args) < 3 {
+		return 0, 0, 0, 0, false
+	}
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+		if err != nil {
+			return 0, 0, 0, 0, false
+		}
+		values[i] = v
+	}
+	if len(args) > 3 {
+		alpha = values[3]
+		if strings.HasSuffix(args[3], "%") {
+			alpha /= 100
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(lower, "rgb"):
+		channel := func(i int) uint8 {
+			if strings.HasSuffix(args[i], "%") {
+				return uint8(math.Round(min(values[i], 100) * 2.55))
+			}
+			return uint8(min(values[i], 255))
+		}
+		return channel(0), channel(1), channel(2), alpha, true
+	case strings.HasPrefix(lower, "hsl"):
+		red, green, blue = hslToRGB(values[0], values[1]/100, values[2]/100)
+		return red, green, blue, alpha, true
+	}
+	return 0, 0, 0, 0, false
+}
+
+// rgbToHSL converts a color to hue in degrees and saturation and lightness
+// in percent
+func rgbToHSL(red, green, blue uint8) (h, s, l int) {
+	rf, gf, bf := float64(red)/255, float64(green)/255, float64(blue)/255
+	hi, lo := max(rf, gf, bf), min(rf, gf, bf)
+	light := (hi + lo) / 2
+	if hi == lo {
+		return 0, 0, int(math.Round(light * 100))
+	}
+
+	d := hi - lo
+	sat := d / (1 - math.Abs(2*light-1))
+	var hue float64
+	switch hi {
+	case rf:
+		hue = math.Mod((gf-bf)/d, 6)
+	case gf:
+		hue = (bf-rf)/d + 2
+	default:
+		hue = (rf-gf)/d + 4
+	}
+	hue *= 60
+	if hue < 0 {
+		hue += 360
+	}
+	return int(math.Round(hue)), int(math.Round(sat * 100)), int(math.Round(light * 100))
+}
+
+// hslToRGB converts hue in degrees and saturation and lightness from 0 to 1
+func hslToRGB(h, s, l float64) (red, green, blue uint8) {
+	h = math.Mod(h, 360)
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var rf, gf, bf float64
+	switch {
+	case h < 60:
+		rf, gf = c, x
+	case h < 120:
+		rf, gf = x, c
+	case h < 180:
+		gf, bf = c, x
+	case h < 240:
+		gf, bf = x, c
+	case h < 300:
+		rf, bf = x, c
+	default:
+		rf, bf = c, x
+	}
+	channel := func(v float64) uint8 {
+		return uint8(math.Round(min(max(v+m, 0), 1) * 255))
+	}
+	return channel(rf), channel(gf), channel(bf)
+}
+
+// hexDump shows binary content as offsets, hex bytes and printable
+// characters, up to the line limit or hexDumpMaxSize
+func (r *renderer) hexDump(content string) []span {
+	data := []byte(content[:min(len(content), hexDumpMaxSize)])
+	// Keep a line for the number of bytes left out
+	if limit := max(r.maxLines-1, 1) * 16; r.maxLines > 0 && len(data) > limit {
+		data = data[:limit]
+	}
+
+	var spans []span
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(hex.Dump(data), "\n"), "\n") {
+		// Offset, then the bytes and characters
+		spans = append(spans, r.muted(line[:8]), span{text: line[8:]})
+	}
+	if len(data) < len(content) {
+		spans = append(spans, r.muted(fmt.Sprintf("\n... %s more", plural(len(content)-len(data), "byte"))))
+	}
+	return spans
+}
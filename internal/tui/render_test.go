@@ -0,0 +1,37 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dvd/cliptui/internal/theme"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+func TestColorSwatch(t *testing.T) {
+	item := types.ClipboardItem{Content: "#ff8800", Type: types.TypeColor}
+
+	dark, err := theme.Load("dark", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	preview := FormatPreview(item, PreviewOptions{}, dark)
+	if !strings.Contains(strings.ToLower(preview.Text), "[#ff8800]█") {
+		t.Errorf("dark preview has no swatch:\n%s", preview.Text)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	noColor, err := theme.Load("dark", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	preview = FormatPreview(item, PreviewOptions{}, noColor)
+	if strings.Contains(preview.Plain, "█") || strings.Contains(strings.ToLower(preview.Text), "#ff8800]") {
+		t.Errorf("preview under NO_COLOR has a swatch:\n%s", preview.Text)
+	}
+	for _, value := range []string{"#ff8800", "rgb(255, 136, 0)", "hsl(32, 100%, 50%)"} {
+		if !strings.Contains(preview.Plain, value) {
+			t.Errorf("preview under NO_COLOR lacks %s:\n%s", value, preview.Plain)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package tui
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dvd/cliptui/internal/config"
+	"github.com/dvd/cliptui/internal/export"
+	"github.com/dvd/cliptui/pkg/types"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// prompt identifies the question asked by the list or preview prompt
+type prompt int
+
+const (
+	promptTag prompt = iota
+	promptExport
+	promptJoin
+	promptFind
+	promptLine
+)
+
+// separators are the names accepted by the join prompt
+var separators = map[string]string{
+	"newline": "\n",
+	"space":   " ",
+	"comma":   ",",
+	"tab":     "\t",
+}
+
+// exportFormats maps file extensions to the format items are exported in
+var exportFormats = map[string]string{
+	".json":   export.FormatJSON,
+	".ndjson": export.FormatNDJSON,
+	".jsonl":  export.FormatNDJSON,
+	".tsv":    export.FormatTSV,
+}
+
+// toggleMark selects or unselects the item under the cursor and moves on
+// to the next one
+func (a *App) toggleMark() {
+	a.state.mu.Lock()
+	if len(a.state.filteredItems) == 0 {
+		a.state.mu.Unlock()
+		return
+	}
+	id := a.state.filteredItems[a.state.cursor].ID
+	if a.state.selected[id] {
+		delete(a.state.selected, id)
+	} else {
+		if a.state.selected == nil {
+			a.state.selected = make(map[int64]bool)
+		}
+		a.state.selected[id] = true
+	}
+	a.state.mu.Unlock()
+
+	a.updateListDisplay()
+	a.moveCursorDown()
+}
+
+// toggleVisualMode starts selecting the items between the cursor and the
+// item it is on now, or stops, keeping what was selected
+func (a *App) toggleVisualMode() {
+	a.state.mu.Lock()
+	if a.state.visualAnchor != 0 {
+		a.state.visualAnchor = 0
+		a.state.visualBase = nil
+	} else if len(a.state.filteredItems) > 0 {
+		a.state.visualAnchor = a.state.filteredItems[a.state.cursor].ID
+		a.state.visualBase = maps.Clone(a.state.selected)
+		a.extendVisual()
+	}
+	a.state.mu.Unlock()
+
+	a.updateListDisplay()
+}
+
+// extendVisual selects the items between the visual anchor and the cursor,
+// on top of the selection from before visual mode started. Visual mode ends
+// when the anchor is no longer listed. The caller must hold a.state.mu.
+func (a *App) extendVisual() {
+	if a.state.visualAnchor == 0 {
+		return
+	}
+	anchor := slices.IndexFunc(a.state.filteredItems, func(item types.ClipboardItem) bool {
+		return item.ID == a.state.visualAnchor
+	})
+	if anchor < 0 {
+		a.state.visualAnchor = 0
+		a.state.visualBase = nil
+		return
+	}
+
+	selected := maps.Clone(a.state.visualBase)
+	if selected == nil {
+		selected = make(map[int64]bool)
+	}
+	from, to := min(anchor, a.state.cursor), max(anchor, a.state.cursor)
+	for _, item := range a.state.filteredItems[from : to+1] {
+		selected[item.ID] = true
+	}
+	a.state.selected = selected
+}
+
+// invertSelection selects the listed items that are not selected and
+// unselects the others. Selected items hidden by a search stay selected.
+func (a *App) invertSelection() {
+	a.state.mu.Lock()
+	a.state.visualAnchor = 0
+	a.state.visualBase = nil
+	if a.state.selected == nil {
+		a.state.selected = make(map[int64]bool)
+	}
+	for _, item := range a.state.filteredItems {
+		if a.state.selected[item.ID] {
+			delete(a.state.selected, item.ID)
+		} else {
+			a.state.selected[item.ID] = true
+		}
+	}
+	a.state.mu.Unlock()
+
+	a.updateListDisplay()
+}
+
+// clearSelection unselects every item and ends visual mode
+func (a *App) clearSelection() {
+	a.state.mu.Lock()
+	a.state.selected = nil
+	a.state.visualAnchor = 0
+	a.state.visualBase = nil
+	a.state.mu.Unlock()
+
+	a.updateListDisplay()
+}
+
+// pruneSelection forgets selected items that are gone from the list. The
+// caller must hold a.state.mu.
+func (a *App) pruneSelection() {
+	if len(a.state.selected) == 0 {
+		return
+	}
+	loaded := make(map[int64]bool, len(a.state.items))
+	for _, item := range a.state.items {
+		loaded[item.ID] = true
+	}
+	maps.DeleteFunc(a.state.selected, func(id int64, _ bool) bool {
+		return !loaded[id]
+	})
+	a.extendVisual()
+}
+
+// targets returns the items a bulk action works on: the selected items in
+// list order, or the item under the cursor when none are selected
+func (a *App) targets() []types.ClipboardItem {
+	a.state.mu.RLock()
+	defer a.state.mu.RUnlock()
+
+	if len(a.state.selected) == 0 {
+		if len(a.state.filteredItems) == 0 {
+			return nil
+		}
+		return []types.ClipboardItem{a.state.filteredItems[a.state.cursor]}
+	}
+
+	var items []types.ClipboardItem
+	for _, item := range a.state.items {
+		if a.state.selected[item.ID] {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// switchToPrompt replaces the list help with a prompt for the argument of
+// a bulk action
+func (a *App) switchToPrompt(kind prompt) {
+	targets := a.targets()
+	if len(targets) == 0 {
+		return
+	}
+
+	what := "the item"
+	if len(targets) > 1 {
+		what = fmt.Sprintf("%d items", len(targets))
+	}
+	var title, placeholder string
+	var complete func(string) []string
+	switch kind {
+	case promptTag:
+		title = fmt.Sprintf(" Tag %s (enter to tag, esc to cancel) ", what)
+		placeholder = "Tag to add, or -tag to remove it"
+	case promptExport:
+		title = fmt.Sprintf(" Export %s (enter to write, esc to cancel) ", what)
+		placeholder = "File ending in .json, .ndjson or .tsv"
+	case promptJoin:
+		title = fmt.Sprintf(" Join %s and copy (enter to copy, esc to cancel) ", what)
+		placeholder = "Separator: newline, space, comma, tab or any text; empty for newline"
+		complete = completeSeparator
+	}
+
+	a.promptKind = kind
+	a.promptInput.SetText("")
+	a.promptInput.SetTitle(tview.Escape(title))
+	a.promptInput.SetPlaceholder(placeholder)
+	a.promptInput.SetAutocompleteFunc(complete)
+	a.mainFlex.RemoveItem(a.listHelp)
+	a.mainFlex.AddItem(a.promptInput, searchInputHeight, 0, true)
+	a.app.SetFocus(a.promptInput)
+}
+
+// exitPrompt puts the list help back in place of the prompt
+func (a *App) exitPrompt() {
+	a.mainFlex.RemoveItem(a.promptInput)
+	a.mainFlex.AddItem(a.listHelp, searchInputHeight, 0, false)
+	a.app.SetFocus(a.listWidget)
+}
+
+// handlePromptDone runs the bulk action the prompt was asked for. The
+// prompt stays open with the error in its title when the action fails.
+func (a *App) handlePromptDone(key tcell.Key) {
+	if key != tcell.KeyEnter {
+		a.exitPrompt()
+		return
+	}
+
+	targets := a.targets()
+	text := a.promptInput.GetText()
+	var err error
+	switch a.promptKind {
+	case promptTag:
+		err = a.tagItems(targets, strings.TrimSpace(text))
+	case promptExport:
+		path := strings.TrimSpace(text)
+		err = exportItems(targets, path)
+		if err == nil {
+			a.notify("Exported %s to %s", plural(len(targets), "item"), path)
+		}
+	case promptJoin:
+		a.exitPrompt()
+		a.state.mu.RLock()
+		stayOpen := a.state.stayOpen
+		a.state.mu.RUnlock()
+		if a.copyToClipboard(joinItems(targets, text), "the joined items") && !stayOpen {
+			a.app.Stop()
+		}
+		a.clearSelection()
+		return
+	}
+	if err != nil {
+		a.promptInput.SetTitle(tview.Escape(fmt.Sprintf(" %s ", err)))
+		return
+	}
+
+	a.exitPrompt()
+	a.clearSelection()
+	a.reloadItems()
+	a.updateListDisplay()
+}
+
+// tagItems adds a tag to items, or removes it when it starts with a dash
+func (a *App) tagItems(items []types.ClipboardItem, tag string) error {
+	tag, remove := strings.CutPrefix(tag, "-")
+	if err := types.ValidateTag(tag); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		has := slices.Contains(item.Tags, tag)
+		var err error
+		switch {
+		case remove && has:
+			err = a.state.storage.SetTags(item.ID, slices.DeleteFunc(slices.Clone(item.Tags), func(t string) bool { return t == tag }))
+		case !remove && !has:
+			err = a.state.storage.SetTags(item.ID, append(slices.Clone(item.Tags), tag))
+		}
+		if err != nil {
+			a.debugLog.Printf("tag item %d: %v", item.ID, err)
+			return fmt.Errorf("item %d: %w", item.ID, err)
+		}
+	}
+
+	if remove {
+		a.notify("Removed %s from %s", tag, plural(len(items), "item"))
+	} else {
+		a.notify("Tagged %s with %s", plural(len(items), "item"), tag)
+	}
+	return nil
+}
+
+// exportItems writes items to a file in the format its extension names
+func exportItems(items []types.ClipboardItem, path string) error {
+	if path == "" {
+		return fmt.Errorf("no file given")
+	}
+	format, ok := exportFormats[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return fmt.Errorf("unknown format of %s, use .json, .ndjson or .tsv", path)
+	}
+
+	// Clipboard history is private, so is the export
+	f, err := os.OpenFile(config.ExpandHome(path), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := export.Write(f, items, export.Options{Format: format}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// joinItems joins the content of items with a separator named in the join
+// prompt, or given as is
+func joinItems(items []types.ClipboardItem, separator string) string {
+	if separator == "" {
+		separator = "newline"
+	}
+	if sep, ok := separators[strings.ToLower(strings.TrimSpace(separator))]; ok {
+		separator = sep
+	}
+
+	contents := make([]string, len(items))
+	for i, item := range items {
+		contents[i] = item.Content
+	}
+	return strings.Join(contents, separator)
+}
+
+// completeSeparator lists the separator names starting with the typed text
+func completeSeparator(text string) []string {
+	text = strings.ToLower(text)
+	if text == "" {
+		return nil
+	}
+	var entries []string
+	for _, name := range slices.Sorted(maps.Keys(separators)) {
+		if strings.HasPrefix(name, text) {
+			entries = append(entries, name)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,128 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/dvd/cliptui/internal/clipboard"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// statusTimeout is how long a success message stays in the status line;
+// errors stay until another message replaces them
+const statusTimeout = 3 * time.Second
+
+// SetDebugLog records the errors the TUI shows, with the action that
+// failed, in w
+func (a *App) SetDebugLog(w io.Writer) {
+	a.debugLog = log.New(w, "", log.LstdFlags|log.Lmicroseconds)
+}
+
+// buildStatusLine creates the status line shared by the list and preview
+// pages, hidden while it is empty
+func (a *App) buildStatusLine() {
+	a.statusLine = tview.NewTextView().
+		SetDynamicColors(true)
+	a.statusLine.SetBorderPadding(0, 0, 2, 2)
+}
+
+// notify shows a success message in the status line for a few seconds
+func (a *App) notify(format string, args ...any) {
+	a.setStatus(fmt.Sprintf("[%s]%s[-]", a.theme.Text.Tag(), tview.Escape(fmt.Sprintf(format, args...))))
+
+	seq := a.statusSeq
+	time.AfterFunc(statusTimeout, func() {
+		a.app.QueueUpdateDraw(func() {
+			if a.statusSeq == seq {
+				a.setStatus("")
+			}
+		})
+	})
+}
+
+// fail shows an error in the status line until another message replaces
+// it, and records it in the debug log. action says what failed, like
+// "delete item 12".
+func (a *App) fail(action string, err error) {
+	a.debugLog.Printf("%s: %v", action, err)
+	a.setStatus(fmt.Sprintf("[%s]%s[-]", a.theme.Error.Tag(), tview.Escape(fmt.Sprintf("Failed to %s: %v", action, err))))
+}
+
+// setStatus replaces the text of the status line, showing it when it is
+// not empty
+func (a *App) setStatus(text string) {
+	a.statusSeq++
+	a.statusLine.SetText(text)
+
+	height := 0
+	if text != "" {
+		height = 1
+	}
+	a.mainFlex.ResizeItem(a.statusLine, height, 0)
+	a.previewFlex.ResizeItem(a.statusLine, height, 0)
+}
+
+// copyToClipboard copies content and reports whether it worked
+func (a *App) copyToClipboard(content, what string) bool {
+	if err := clipboard.SetClipboard(content); err != nil {
+		a.fail("copy "+what, err)
+		return false
+	}
+	a.notify("Copied %s", plural(len(content), "byte"))
+	return true
+}
+
+// confirm asks a yes or no question in a dialog over the current page and
+// runs onYes if the button labelled action or y is pressed
+func (a *App) confirm(question, action string, onYes func()) {
+	focus := a.app.GetFocus()
+	modal := tview.NewModal().
+		SetText(question).
+		AddButtons([]string{action, "Cancel"}).
+		SetFocus(1).
+		SetBackgroundColor(tcell.ColorDefault).
+		SetTextColor(a.theme.Text.Color).
+		SetButtonStyle(tcell.StyleDefault.Foreground(a.theme.Text.Color)).
+		SetButtonActivatedStyle(a.theme.SelectionStyle())
+	modal.SetBorderColor(a.theme.Error.Color).
+		SetTitleColor(a.theme.Error.Color).
+		SetTitle(" Confirm ")
+
+	answer := func(yes bool) {
+		a.pages.RemovePage("confirm")
+		a.app.SetFocus(focus)
+		if yes {
+			onYes()
+		}
+	}
+	modal.SetDoneFunc(func(_ int, label string) {
+		answer(label == action)
+	})
+
+	// y and n answer without moving to a button
+	modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'y':
+			answer(true)
+			return nil
+		case 'n':
+			answer(false)
+			return nil
+		}
+		return event
+	})
+
+	a.pages.AddPage("confirm", modal, true, true)
+	a.app.SetFocus(modal)
+}
+
+// plural counts things in words, like "1 item" or "3 items"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -4,6 +4,10 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/dvd/cliptui/internal/theme"
+	"github.com/rivo/tview"
 )
 
 // Mode types
@@ -37,14 +41,52 @@ func formatTimestamp(t time.Time) string {
 	}
 }
 
-// truncate shortens a string and replaces newlines
-func truncate(s string, maxLen int) string {
-	s = strings.ReplaceAll(s, "\n", " ")
-	s = strings.ReplaceAll(s, "\t", " ")
-
-	if len(s) <= maxLen {
-		return s
+// formatPaused describes a paused capture for the list title
+func formatPaused(until time.Time, color theme.Color) string {
+	if until.IsZero() {
+		return fmt.Sprintf("[%s::b]⏸ capture paused[-::-]", color.Tag())
 	}
-	return s[:maxLen-3] + "..."
+	remaining := time.Until(until).Round(time.Second)
+	return fmt.Sprintf("[%s::b]⏸ capture paused (%s left)[-::-]", color.Tag(), remaining)
 }
 
+// highlightMatches escapes text for a table cell and marks the characters
+// taken from the byte offsets in positions. offsets gives the source offset
+// of every byte of text, -1 for bytes that were added; offsets at or after
+// limit are never marked.
+func highlightMatches(text string, offsets, positions []int, limit int, color theme.Color) string {
+	if len(positions) == 0 {
+		return tview.Escape(text)
+	}
+
+	matched := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		if p < limit {
+			matched[p] = true
+		}
+	}
+
+	var b strings.Builder
+	open := false
+	segment := 0 // start of the text not yet written
+	for i := 0; i < len(text); {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		hit := offsets[i] >= 0 && matched[offsets[i]]
+		if hit != open {
+			b.WriteString(tview.Escape(text[segment:i]))
+			if hit {
+				fmt.Fprintf(&b, "[%s::b]", color.Tag())
+			} else {
+				b.WriteString("[-::-]")
+			}
+			segment = i
+			open = hit
+		}
+		i += size
+	}
+	b.WriteString(tview.Escape(text[segment:]))
+	if open {
+		b.WriteString("[-::-]")
+	}
+	return b.String()
+}
@@ -0,0 +1,195 @@
+package typerules
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dvd/cliptui/internal/search"
+	"github.com/dvd/cliptui/internal/theme"
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+// Actions a rule can run on the items of its type when they are captured
+const (
+	ActionPin    = "pin"    // pin the item
+	ActionSkip   = "skip"   // do not store the item
+	ActionTag    = "tag"    // tag:<tag> adds a tag
+	ActionExpire = "expire" // expire:<duration> deletes the item after a while
+)
+
+// namePattern keeps type names usable as a single "type:<name>" query term
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Rule defines a type for content the built-in detection does not know,
+// like ticket IDs or internal host names. It is read from a [[types]] table
+// of the config.
+type Rule struct {
+	Name string `toml:"name"`
+	// Pattern is a regular expression the content, without surrounding
+	// whitespace, must match
+	Pattern string `toml:"pattern"`
+	// Predicate is a search query the content must match, like "len<40 -http"
+	Predicate string `toml:"predicate"`
+	// Priority orders the rules, highest first; equal ones keep their order
+	Priority int `toml:"priority"`
+	// Color is the color of the type's badge in the list
+	Color theme.Color `toml:"color"`
+	// Actions run when an item of the type is captured
+	Actions []string `toml:"actions"`
+}
+
+// rule is a Rule ready to be matched
+type rule struct {
+	Rule
+	pattern   *regexp.Regexp
+	predicate search.And
+}
+
+// Set is a list of checked rules in the order they are tried
+type Set struct {
+	rules []rule
+}
+
+// New checks and compiles rules. A rule needs a pattern, a predicate or
+// both, and a name that is not taken by a built-in type or another rule.
+func New(rules []Rule) (*Set, error) {
+	set := &Set{}
+	for i, r := range rules {
+		compiled, err := compile(r)
+		if err != nil {
+			if r.Name == "" {
+				return nil, fmt.Errorf("rule %d: %w", i+1, err)
+			}
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		if slices.ContainsFunc(set.rules, func(other rule) bool { return other.Name == r.Name }) {
+			return nil, fmt.Errorf("rule %q: defined twice", r.Name)
+		}
+		set.rules = append(set.rules, compiled)
+	}
+
+	sort.SliceStable(set.rules, func(i, j int) bool {
+		return set.rules[i].Priority > set.rules[j].Priority
+	})
+	return set, nil
+}
+
+// compile checks a single rule
+func compile(r Rule) (rule, error) {
+	compiled := rule{Rule: r}
+	if !namePattern.MatchString(r.Name) {
+		return compiled, fmt.Errorf("name must be lowercase letters, digits, - and _")
+	}
+	if slices.Contains(types.Types, r.Name) {
+		return compiled, fmt.Errorf("%q is a built-in type", r.Name)
+	}
+	if r.Pattern == "" && r.Predicate == "" {
+		return compiled, fmt.Errorf("needs a pattern or a predicate")
+	}
+
+	if r.Pattern != "" {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return compiled, fmt.Errorf("invalid pattern: %w", err)
+		}
+		compiled.pattern = re
+	}
+	if r.Predicate != "" {
+		predicate, err := search.Parse(r.Predicate)
+		if err != nil {
+			return compiled, fmt.Errorf("invalid predicate: %w", err)
+		}
+		compiled.predicate = predicate
+	}
+
+	for _, action := range r.Actions {
+		if err := checkAction(action); err != nil {
+			return compiled, err
+		}
+	}
+	return compiled, nil
+}
+
+// checkAction reports actions that cannot be run
+func checkAction(action string) error {
+	name, arg, _ := strings.Cut(action, ":")
+	switch name {
+	case ActionPin, ActionSkip:
+		if arg != "" {
+			return fmt.Errorf("action %s takes no argument", name)
+		}
+		return nil
+	case ActionTag:
+		return types.ValidateTag(arg)
+	case ActionExpire:
+		d, err := time.ParseDuration(arg)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("action expire needs a positive duration like expire:1h, got %q", arg)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown action %q, expected pin, skip, tag:<tag> or expire:<duration>", action)
+}
+
+// match reports whether content is of the rule's type
+func (r rule) match(content string) bool {
+	if r.pattern != nil && !r.pattern.MatchString(strings.TrimSpace(content)) {
+		return false
+	}
+	if r.predicate != nil && !r.predicate.Match(types.ClipboardItem{Content: content}) {
+		return false
+	}
+	return true
+}
+
+// Install makes the rules part of type detection, replacing the rules
+// installed before. Their types are detected before any built-in type.
+func (s *Set) Install() {
+	custom := make([]types.CustomType, len(s.rules))
+	for i, r := range s.rules {
+		custom[i] = types.CustomType{Name: r.Name, Match: r.match}
+	}
+	types.SetCustomTypes(custom)
+}
+
+// Color returns the badge color of a type defined by a rule
+func (s *Set) Color(itemType string) (theme.Color, bool) {
+	for _, r := range s.rules {
+		if r.Name == itemType {
+			return r.Color, true
+		}
+	}
+	return theme.Color{}, false
+}
+
+// Apply runs the actions of the rule that defines the item's type. It
+// returns false when the item should not be stored.
+func (s *Set) Apply(item types.ClipboardItem) (types.ClipboardItem, bool) {
+	for _, r := range s.rules {
+		if r.Name != item.Type {
+			continue
+		}
+		for _, action := range r.Actions {
+			name, arg, _ := strings.Cut(action, ":")
+			switch name {
+			case ActionPin:
+				item.Pinned = true
+			case ActionSkip:
+				return item, false
+			case ActionTag:
+				if !slices.Contains(item.Tags, arg) {
+					item.Tags = append(item.Tags, arg)
+				}
+			case ActionExpire:
+				d, _ := time.ParseDuration(arg)
+				item.ExpiresAt = time.Now().Add(d)
+			}
+		}
+		break
+	}
+	return item, true
+}
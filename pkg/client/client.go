@@ -0,0 +1,251 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+// dialTimeout bounds how long Dial waits for the daemon to accept a connection
+const dialTimeout = 500 * time.Millisecond
+
+// Client talks to a running cliptui daemon over its Unix socket.
+// It implements the same methods as storage.Store, so it can be used
+// anywhere the database would be opened directly.
+type Client struct {
+	path string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	enc    *json.Encoder
+	dec    *json.Decoder
+	nextID int64
+}
+
+// Dial connects to the daemon listening on the socket at path
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		path: path,
+		conn: conn,
+		enc:  json.NewEncoder(conn),
+		dec:  json.NewDecoder(conn),
+	}, nil
+}
+
+// call sends a request and decodes the result into out, if out is non-nil
+func (c *Client) call(method string, params any, out any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	req := Request{ID: c.nextID, Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.Params = raw
+	}
+
+	if err := c.enc.Encode(req); err != nil {
+		return err
+	}
+
+	var resp Response
+	if err := c.dec.Decode(&resp); err != nil {
+		return err
+	}
+	if resp.ID != req.ID {
+		return fmt.Errorf("daemon answered request %d, expected %d", resp.ID, req.ID)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+
+	if out != nil && len(resp.Result) > 0 {
+		return json.Unmarshal(resp.Result, out)
+	}
+	return nil
+}
+
+// item calls a method that returns a single item, or nil
+func (c *Client) item(method string, params any) (*types.ClipboardItem, error) {
+	var item *Item
+	if err := c.call(method, params, &item); err != nil || item == nil {
+		return nil, err
+	}
+	unwrapped := item.Unwrap()
+	return &unwrapped, nil
+}
+
+// items calls a method that returns a list of items
+func (c *Client) items(method string, params any) ([]types.ClipboardItem, error) {
+	var wire []Item
+	if err := c.call(method, params, &wire); err != nil {
+		return nil, err
+	}
+	if wire == nil {
+		return nil, nil
+	}
+	items := make([]types.ClipboardItem, len(wire))
+	for i, item := range wire {
+		items[i] = item.Unwrap()
+	}
+	return items, nil
+}
+
+// Add inserts a new clipboard item
+func (c *Client) Add(content string) error {
+	return c.call(MethodAdd, AddParams{Content: []byte(content)}, nil)
+}
+
+// AddItem inserts an item as given and returns it with its ID
+func (c *Client) AddItem(item types.ClipboardItem) (*types.ClipboardItem, error) {
+	return c.item(MethodAddItem, NewItem(item))
+}
+
+// GetAll retrieves all clipboard items, newest first
+func (c *Client) GetAll() ([]types.ClipboardItem, error) {
+	return c.GetRecent(0)
+}
+
+// GetRecent retrieves the N most recent items
+func (c *Client) GetRecent(limit int) ([]types.ClipboardItem, error) {
+	return c.items(MethodList, ListParams{Limit: limit})
+}
+
+// List returns the items matching q, newest first
+func (c *Client) List(q types.Query) ([]types.ClipboardItem, error) {
+	return c.items(MethodQuery, q)
+}
+
+// Get returns the item with the given ID, or nil if it does not exist
+func (c *Client) Get(id int64) (*types.ClipboardItem, error) {
+	return c.item(MethodGet, IDParams{ID: id})
+}
+
+// Search returns up to limit items whose content contains query
+func (c *Client) Search(query string, limit int) ([]types.ClipboardItem, error) {
+	return c.items(MethodSearch, SearchParams{Query: query, Limit: limit})
+}
+
+// SetPinned pins or unpins an item
+func (c *Client) SetPinned(id int64, pinned bool) error {
+	return c.call(MethodPin, PinParams{ID: id, Pinned: pinned}, nil)
+}
+
+// SetType changes the type of an item
+func (c *Client) SetType(id int64, itemType string) error {
+	return c.call(MethodSetType, SetTypeParams{ID: id, Type: itemType}, nil)
+}
+
+// SetLanguage sets the programming language of an item
+func (c *Client) SetLanguage(id int64, language string) error {
+	return c.call(MethodSetLanguage, SetLanguageParams{ID: id, Language: language}, nil)
+}
+
+// SetTags replaces the tags of an item
+func (c *Client) SetTags(id int64, tags []string) error {
+	return c.call(MethodSetTags, SetTagsParams{ID: id, Tags: tags}, nil)
+}
+
+// Touch moves an item to the top of the history by making it the newest
+func (c *Client) Touch(id int64) error {
+	return c.call(MethodTouch, IDParams{ID: id}, nil)
+}
+
+// Delete removes an item by ID
+func (c *Client) Delete(id int64) error {
+	return c.call(MethodDelete, IDParams{ID: id}, nil)
+}
+
+// Clear removes all items
+func (c *Client) Clear() error {
+	return c.call(MethodClear, nil, nil)
+}
+
+// GetLatest returns the most recent item
+func (c *Client) GetLatest() (*types.ClipboardItem, error) {
+	return c.item(MethodLatest, nil)
+}
+
+// Pause asks the daemon to stop capturing clipboard changes for d,
+// or until Resume is called if d is zero
+func (c *Client) Pause(d time.Duration) error {
+	return c.call(MethodPause, PauseParams{Duration: d}, nil)
+}
+
+// Resume asks the daemon to continue capturing clipboard changes
+func (c *Client) Resume() error {
+	return c.call(MethodResume, nil, nil)
+}
+
+// Status reports the state of the running daemon
+func (c *Client) Status() (*Status, error) {
+	var status Status
+	if err := c.call(MethodStatus, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Shutdown asks the daemon to stop gracefully
+func (c *Client) Shutdown() error {
+	return c.call(MethodShutdown, nil, nil)
+}
+
+// Subscribe opens a separate connection that streams history changes until
+// ctx is cancelled or the daemon goes away, at which point the channel is closed
+func (c *Client) Subscribe(ctx context.Context) (<-chan types.Event, error) {
+	sub, err := Dial(c.path)
+	if err != nil {
+		return nil, err
+	}
+	if err := sub.call(MethodSubscribe, nil, nil); err != nil {
+		sub.Close()
+		return nil, err
+	}
+
+	events := make(chan types.Event)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		sub.Close()
+	}()
+	go func() {
+		defer close(events)
+		defer close(done)
+		for {
+			var event Event
+			if err := sub.dec.Decode(&event); err != nil {
+				return
+			}
+			select {
+			case events <- event.Unwrap():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// Close closes the connection to the daemon
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dvd/cliptui/pkg/types"
+)
+
+// The daemon speaks line-delimited JSON over a Unix socket: every line sent
+// by a client is a Request and every line sent back is a Response. After a
+// successful subscribe request the connection switches to streaming, and
+// each following line is an Event.
+//
+// Items always travel as an Item, so that binary content survives JSON.
+
+// Method names understood by the daemon
+const (
+	MethodList        = "list"
+	MethodQuery       = "query" // params are a types.Query
+	MethodGet         = "get"
+	MethodLatest      = "latest"
+	MethodAdd         = "add"
+	MethodAddItem     = "add_item" // params are an Item
+	MethodDelete      = "delete"
+	MethodClear       = "clear"
+	MethodPin         = "pin"
+	MethodSetType     = "set_type"
+	MethodSetLanguage = "set_language"
+	MethodSetTags     = "set_tags"
+	MethodTouch       = "touch"
+	MethodSearch      = "search"
+	MethodPause       = "pause"
+	MethodResume      = "resume"
+	MethodSubscribe   = "subscribe"
+	MethodStatus      = "status"
+	MethodShutdown    = "shutdown"
+)
+
+// Request is a single call sent to the daemon
+type Request struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is the daemon's answer to a Request with the same ID
+type Response struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ListParams are the parameters of the list method, a zero limit returns everything
+type ListParams struct {
+	Limit int `json:"limit"`
+}
+
+// IDParams are the parameters of methods that address a single item
+type IDParams struct {
+	ID int64 `json:"id"`
+}
+
+// AddParams are the parameters of the add method. Content is sent base64
+// encoded, as it may not be valid UTF-8.
+type AddParams struct {
+	Content []byte `json:"content"`
+}
+
+// Item is a types.ClipboardItem as it is sent over the socket. JSON strings
+// cannot hold bytes that are not valid UTF-8, which encoding replaces with
+// U+FFFD, so such content is sent base64 encoded in Data and Content is left
+// empty.
+type Item struct {
+	types.ClipboardItem
+	Data []byte `json:"data,omitempty"`
+}
+
+// NewItem prepares item to be sent over the socket
+func NewItem(item types.ClipboardItem) Item {
+	if utf8.ValidString(item.Content) {
+		return Item{ClipboardItem: item}
+	}
+	data := []byte(item.Content)
+	item.Content = ""
+	return Item{ClipboardItem: item, Data: data}
+}
+
+// NewItems prepares a list of items to be sent over the socket
+func NewItems(items []types.ClipboardItem) []Item {
+	wire := make([]Item, len(items))
+	for i, item := range items {
+		wire[i] = NewItem(item)
+	}
+	return wire
+}
+
+// Unwrap returns the item that was sent
+func (i Item) Unwrap() types.ClipboardItem {
+	item := i.ClipboardItem
+	if i.Data != nil {
+		item.Content = string(i.Data)
+	}
+	return item
+}
+
+// Event is a types.Event as it is streamed to subscribers
+type Event struct {
+	Kind string `json:"kind"`
+	ID   int64  `json:"id,omitempty"`
+	Item *Item  `json:"item,omitempty"`
+}
+
+// NewEvent prepares event to be streamed to subscribers
+func NewEvent(event types.Event) Event {
+	wire := Event{Kind: event.Kind, ID: event.ID}
+	if event.Item != nil {
+		item := NewItem(*event.Item)
+		wire.Item = &item
+	}
+	return wire
+}
+
+// Unwrap returns the event that was streamed
+func (e Event) Unwrap() types.Event {
+	event := types.Event{Kind: e.Kind, ID: e.ID}
+	if e.Item != nil {
+		item := e.Item.Unwrap()
+		event.Item = &item
+	}
+	return event
+}
+
+// PinParams are the parameters of the pin method
+type PinParams struct {
+	ID     int64 `json:"id"`
+	Pinned bool  `json:"pinned"`
+}
+
+// SetTypeParams are the parameters of the set_type method
+type SetTypeParams struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// SetLanguageParams are the parameters of the set_language method
+type SetLanguageParams struct {
+	ID       int64  `json:"id"`
+	Language string `json:"language"`
+}
+
+// SetTagsParams are the parameters of the set_tags method
+type SetTagsParams struct {
+	ID   int64    `json:"id"`
+	Tags []string `json:"tags"`
+}
+
+// PauseParams are the parameters of the pause method, a zero duration
+// pauses until resume is called
+type PauseParams struct {
+	Duration time.Duration `json:"duration"`
+}
+
+// SearchParams are the parameters of the search method
+type SearchParams struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+// Status describes the running daemon
+type Status struct {
+	PID         int       `json:"pid"`
+	StartedAt   time.Time `json:"started_at"`
+	Captures    int       `json:"captures"`
+	LastCapture time.Time `json:"last_capture,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	Backend     string    `json:"backend"`
+	Database    string    `json:"database"`
+	Socket      string    `json:"socket"`
+	Paused      bool      `json:"paused"`
+	PausedUntil time.Time `json:"paused_until,omitempty"`
+}
@@ -0,0 +1,555 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minConfidence is the confidence a detector needs for its type to be used;
+// below it content is plain text
+const minConfidence = 0.5
+
+// maxLineLength bounds single-line types such as paths and emails
+const maxLineLength = 4096
+
+// Detection is the verdict of a detector
+type Detection struct {
+	Type       string
+	Confidence float64 // from 0 to 1
+}
+
+// Detector recognizes one type of content, returning how confident it is
+// that content is of that type, from 0 to 1
+type Detector struct {
+	Type   string
+	Detect func(content string) float64
+}
+
+// CustomType is a type defined by the user, such as a ticket ID format
+type CustomType struct {
+	Name  string
+	Match func(content string) bool
+}
+
+var (
+	customMu sync.RWMutex
+	// customTypes are checked in order before the detectors
+	customTypes []CustomType
+)
+
+// SetCustomTypes replaces the user-defined types. They are checked in order
+// before the built-in detectors, the first one to match wins, and their names
+// are accepted wherever a type is.
+func SetCustomTypes(custom []CustomType) {
+	customMu.Lock()
+	customTypes = custom
+	customMu.Unlock()
+}
+
+// KnownType reports whether name is a built-in or user-defined type
+func KnownType(name string) bool {
+	return slices.Contains(AllTypes(), name)
+}
+
+// AllTypes lists the built-in types followed by the user-defined ones
+func AllTypes() []string {
+	customMu.RLock()
+	defer customMu.RUnlock()
+
+	all := slices.Clone(Types)
+	for _, t := range customTypes {
+		all = append(all, t.Name)
+	}
+	return all
+}
+
+// detectors run in order; the most confident one wins and earlier ones win ties
+var detectors = []Detector{
+	{TypeUUID, detectUUID},
+	{TypeURL, detectURL},
+	{TypeEmail, detectEmail},
+	{TypeIP, detectIP},
+	{TypeColor, detectColor},
+	{TypePath, detectPath},
+	{TypeJSON, detectJSON},
+	{TypeDiff, detectDiff},
+	{TypeHTML, detectHTML},
+	{TypeXML, detectXML},
+	{TypeYAML, detectYAML},
+	{TypeShell, detectShell},
+	{TypeBase64, detectBase64},
+	{TypeCode, detectCode},
+	{TypeMarkdown, detectMarkdown},
+}
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	emailPattern    = regexp.MustCompile(`^(mailto:)?[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$`)
+	hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+	funcColor       = regexp.MustCompile(`^(rgb|rgba|hsl|hsla)\(\s*[\d.]+%?\s*(,\s*[\d.]+%?\s*){2,3}\)$|^(rgb|rgba|hsl|hsla)\(\s*[\d.]+%?(\s+[\d.]+%?){2}(\s*/\s*[\d.]+%?)?\s*\)$`)
+	windowsPath     = regexp.MustCompile(`^[A-Za-z]:\\`)
+	yamlKeyLine     = regexp.MustCompile(`^\s*(- )?["']?[\w.-]+["']?:(\s|$)`)
+	yamlListLine    = regexp.MustCompile(`^\s*- \S`)
+	diffHunk        = regexp.MustCompile(`(?m)^@@ -\d+(,\d+)? \+\d+(,\d+)? @@`)
+	markdownHeading = regexp.MustCompile(`(?m)^#{1,6} \S`)
+	markdownList    = regexp.MustCompile(`(?m)^\s*([-*+]|\d+\.) \S`)
+	markdownLink    = regexp.MustCompile(`\[[^\]\n]+\]\([^)\s]+\)`)
+	markdownInline  = regexp.MustCompile("\\*\\*[^*\n]+\\*\\*|__[^_\n]+__|`[^`\n]+`")
+	htmlTag         = regexp.MustCompile(`(?i)<!doctype html|<(html|head|body|div|span|p|a|img|script|style|table|ul|li|br)[\s/>]`)
+)
+
+// Line prefixes and fragments that suggest source code
+var (
+	codeLinePrefixes = []string{
+		"func ", "def ", "class ", "import ", "package ", "const ", "let ",
+		"var ", "return ", "if (", "for (", "while (", "public ", "private ",
+		"#include", "fn ", "pub ", "struct ", "interface ", "export ",
+		"async ", "function ", "//", "/*",
+	}
+	codeFragments = []string{"=>", "::", "!=", "==", ":=", "&&", "||", "();", ") {"}
+)
+
+// Programs that commonly start a copied command line. Strong ones are
+// rarely the first word of a sentence; weak ones need a flag, path or
+// operator on the line as well.
+var (
+	strongCommands = []string{
+		"sudo", "git", "docker", "kubectl", "npm", "npx", "yarn", "pnpm", "pip",
+		"cargo", "curl", "wget", "ssh", "scp", "rsync", "chmod", "chown", "mkdir",
+		"apt", "apt-get", "brew", "dnf", "pacman", "systemctl", "journalctl",
+		"helm", "terraform", "ansible", "podman", "tmux", "nvim", "xargs",
+	}
+	weakCommands = []string{
+		"cd", "ls", "cat", "grep", "find", "go", "make", "tar", "rm", "cp", "mv",
+		"echo", "export", "sed", "awk", "python", "python3", "node", "vim",
+		"touch", "ln",
+	}
+)
+
+// Detect returns the first matching user-defined type, otherwise it runs
+// every detector on content and returns the most confident verdict, or text
+// when no detector is confident enough
+func Detect(content string) Detection {
+	best := Detection{Type: TypeText}
+	if strings.TrimSpace(content) == "" {
+		return best
+	}
+
+	customMu.RLock()
+	custom := customTypes
+	customMu.RUnlock()
+	for _, t := range custom {
+		if t.Match(content) {
+			return Detection{Type: t.Name, Confidence: 1}
+		}
+	}
+
+	for _, d := range detectors {
+		confidence := d.Detect(content)
+		if confidence >= minConfidence && confidence > best.Confidence {
+			best = Detection{Type: d.Type, Confidence: confidence}
+		}
+	}
+	return best
+}
+
+// DetectType attempts to determine the content type
+func DetectType(content string) string {
+	return Detect(content).Type
+}
+
+// singleLine returns trimmed content and whether it is a single short line
+func singleLine(content string) (string, bool) {
+	s := strings.TrimSpace(content)
+	return s, len(s) <= maxLineLength && !strings.ContainsAny(s, "\r\n")
+}
+
+// nonEmptyLines returns the lines of content that are not blank
+func nonEmptyLines(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func detectUUID(content string) float64 {
+	if s, ok := singleLine(content); ok && uuidPattern.MatchString(s) {
+		return 1
+	}
+	return 0
+}
+
+func detectURL(content string) float64 {
+	s, ok := singleLine(content)
+	if !ok || len(s) >= maxURLLength || strings.ContainsAny(s, " \t") {
+		return 0
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return 0
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return 0.95
+	case "ftp", "ftps", "ws", "wss", "ssh", "git", "file":
+		return 0.85
+	}
+	return 0
+}
+
+func detectEmail(content string) float64 {
+	if s, ok := singleLine(content); ok && emailPattern.MatchString(s) {
+		return 0.95
+	}
+	return 0
+}
+
+func detectIP(content string) float64 {
+	s, ok := singleLine(content)
+	if !ok {
+		return 0
+	}
+	if _, err := netip.ParseAddr(s); err == nil {
+		return 0.95
+	}
+	if _, err := netip.ParsePrefix(s); err == nil {
+		return 0.95
+	}
+	if _, err := netip.ParseAddrPort(s); err == nil {
+		return 0.9
+	}
+	return 0
+}
+
+func detectColor(content string) float64 {
+	s, ok := singleLine(content)
+	if !ok {
+		return 0
+	}
+	if hexColorPattern.MatchString(s) {
+		// "#123" is as likely to be an issue number as a color
+		if strings.Trim(s[1:], "0123456789") == "" && len(s) <= 5 {
+			return 0.4
+		}
+		return 0.95
+	}
+	if funcColor.MatchString(strings.ToLower(s)) {
+		return 0.95
+	}
+	return 0
+}
+
+func detectPath(content string) float64 {
+	s, ok := singleLine(content)
+	if !ok || len(s) < 2 || strings.Contains(s, "://") {
+		return 0
+	}
+	switch {
+	case strings.HasPrefix(s, "~/"), strings.HasPrefix(s, "./"), strings.HasPrefix(s, "../"):
+		return 0.9
+	case windowsPath.MatchString(s):
+		return 0.9
+	case strings.HasPrefix(s, "/"):
+		// Spaces are allowed in paths but rarely appear in copied ones
+		if strings.Contains(s, " ") {
+			return 0.55
+		}
+		return 0.9
+	}
+	return 0
+}
+
+func detectJSON(content string) float64 {
+	s := strings.TrimSpace(content)
+	if len(s) < 2 {
+		return 0
+	}
+	// Bare numbers and strings are valid JSON but not worth calling JSON
+	if (s[0] != '{' || s[len(s)-1] != '}') && (s[0] != '[' || s[len(s)-1] != ']') {
+		return 0
+	}
+	if json.Valid([]byte(s)) {
+		return 0.98
+	}
+	return 0
+}
+
+func detectDiff(content string) float64 {
+	if strings.HasPrefix(content, "diff --git ") {
+		return 0.98
+	}
+	if !diffHunk.MatchString(content) {
+		return 0
+	}
+	if (strings.HasPrefix(content, "--- ") || strings.Contains(content, "\n--- ")) &&
+		strings.Contains(content, "\n+++ ") {
+		return 0.95
+	}
+	return 0.7
+}
+
+func detectHTML(content string) float64 {
+	s := strings.TrimSpace(content)
+	if !strings.HasPrefix(s, "<") || !strings.HasSuffix(s, ">") {
+		return 0
+	}
+	lower := strings.ToLower(s)
+	if strings.HasPrefix(lower, "<!doctype html") || strings.HasPrefix(lower, "<html") {
+		return 0.98
+	}
+	if loc := htmlTag.FindStringIndex(s); loc != nil {
+		// Fragments are often valid XML too, but one that starts with an
+		// HTML element is HTML
+		if loc[0] == 0 {
+			return 0.95
+		}
+		return 0.85
+	}
+	return 0
+}
+
+func detectXML(content string) float64 {
+	s := strings.TrimSpace(content)
+	if !strings.HasPrefix(s, "<") || !strings.HasSuffix(s, ">") {
+		return 0
+	}
+
+	dec := xml.NewDecoder(strings.NewReader(s))
+	elements := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			elements++
+		}
+	}
+	if elements == 0 {
+		return 0
+	}
+	if strings.HasPrefix(s, "<?xml") {
+		return 0.98
+	}
+	return 0.9
+}
+
+func detectYAML(content string) float64 {
+	lines := nonEmptyLines(content)
+	if len(lines) < 2 {
+		return 0
+	}
+
+	document := strings.HasPrefix(strings.TrimSpace(content), "---")
+	keys, matched, total := 0, 0, 0
+	// Nested maps and lists set YAML apart from a few "Key: value" lines of
+	// prose, which only count as a flat map when none reads like a sentence
+	structured, prose := document, false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") || (i == 0 && trimmed == "---") {
+			continue
+		}
+		total++
+		switch {
+		case yamlKeyLine.MatchString(line):
+			keys++
+			matched++
+			if strings.HasPrefix(trimmed, "- ") {
+				structured = true
+			} else if strings.HasSuffix(trimmed, ":") {
+				structured = structured || (i+1 < len(lines) &&
+					(indentOf(lines[i+1]) > indentOf(line) || yamlListLine.MatchString(lines[i+1])))
+			} else if proseLine(trimmed) {
+				prose = true
+			}
+		case yamlListLine.MatchString(line):
+			matched++
+			structured = true
+		case strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"):
+			// Continuation of a multi-line value
+			matched++
+		}
+	}
+	if keys == 0 || total == 0 || (prose && !structured) {
+		return 0
+	}
+	confidence := float64(matched) / float64(total) * 0.9
+	if document {
+		confidence += 0.05
+	}
+	return confidence
+}
+
+// indentOf returns the width of the leading whitespace of line
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// proseLine reports whether a "key: value" line reads like prose rather than
+// configuration: the key is capitalized, like a mail header or a note, or the
+// value is several words ending a sentence
+func proseLine(line string) bool {
+	key, value, _ := strings.Cut(line, ":")
+	key = strings.Trim(key, `"'`)
+	if r, _ := utf8.DecodeRuneInString(key); unicode.IsUpper(r) {
+		return true
+	}
+	value = strings.TrimSpace(value)
+	return strings.Contains(value, " ") && strings.ContainsAny(value[len(value)-1:], ".!?")
+}
+
+func detectShell(content string) float64 {
+	lines := nonEmptyLines(content)
+	if len(lines) == 0 || len(lines) > 20 {
+		return 0
+	}
+
+	if strings.HasPrefix(content, "#!") && strings.Contains(lines[0], "sh") {
+		return 0.95
+	}
+
+	commands := 0
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if rest, ok := strings.CutPrefix(line, "$ "); ok {
+			if rest != "" {
+				commands++
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		switch {
+		case slices.Contains(strongCommands, fields[0]):
+			commands++
+		case slices.Contains(weakCommands, fields[0]):
+			if len(fields) == 1 || strings.ContainsAny(line, "/|>$=*~") ||
+				slices.ContainsFunc(fields[1:], func(f string) bool { return strings.HasPrefix(f, "-") }) {
+				commands++
+			}
+		}
+	}
+	if commands == 0 {
+		return 0
+	}
+
+	confidence := 0.5 + 0.4*float64(commands)/float64(len(lines))
+	if strings.Contains(content, " | ") || strings.Contains(content, " && ") {
+		confidence += 0.05
+	}
+	return confidence
+}
+
+func detectBase64(content string) float64 {
+	s, ok := singleLine(content)
+	if !ok || len(s) < 16 || strings.ContainsAny(s, " \t") {
+		return 0
+	}
+
+	// Hex strings, words and identifiers are often valid base64 too. Encoded
+	// data mixes cases and digits and has no long runs of lowercase letters.
+	upper, lower, digits, run, longestRun := 0, 0, 0, 0, 0
+	for _, r := range s {
+		run++
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+			run = 0
+		case unicode.IsLower(r):
+			lower++
+		case unicode.IsDigit(r):
+			digits++
+			run = 0
+		default:
+			run = 0
+		}
+		longestRun = max(longestRun, run)
+	}
+	if upper < 2 || lower < 2 || digits+strings.Count(s, "+")+strings.Count(s, "/") == 0 || longestRun > 8 {
+		return 0
+	}
+
+	padded := strings.HasSuffix(s, "=")
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if _, err := enc.DecodeString(s); err != nil {
+			continue
+		}
+		if padded || len(s) >= 32 {
+			return 0.85
+		}
+		return 0.6
+	}
+	return 0
+}
+
+func detectCode(content string) float64 {
+	lines := nonEmptyLines(content)
+	if len(lines) == 0 {
+		return 0
+	}
+	// Scripts for other interpreters than the shell
+	if strings.HasPrefix(content, "#!") {
+		return 0.9
+	}
+
+	signals := 0
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasSuffix(trimmed, ";"), strings.HasSuffix(trimmed, "{"), trimmed == "}":
+			signals++
+			continue
+		}
+		for _, prefix := range codeLinePrefixes {
+			if strings.HasPrefix(trimmed, prefix) {
+				signals++
+				break
+			}
+		}
+	}
+	for _, fragment := range codeFragments {
+		if strings.Contains(content, fragment) {
+			signals++
+		}
+	}
+
+	// One signal is easily an accident in prose; it takes two to be code
+	if signals < 2 {
+		return 0
+	}
+	return min(0.4+0.1*float64(signals), 0.9)
+}
+
+func detectMarkdown(content string) float64 {
+	signals := 2 * len(markdownHeading.FindAllStringIndex(content, -1))
+	if strings.Contains(content, "```") {
+		signals += 2
+	}
+	if lists := len(markdownList.FindAllStringIndex(content, -1)); lists >= 2 {
+		signals += lists
+	}
+	signals += len(markdownLink.FindAllStringIndex(content, -1))
+	signals += len(markdownInline.FindAllStringIndex(content, -1))
+
+	if signals < 2 {
+		return 0
+	}
+	return min(0.4+0.1*float64(signals), 0.9)
+}
@@ -0,0 +1,172 @@
+package types
+
+import "testing"
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		// Text
+		{"empty", "", TypeText},
+		{"blank", " \n\t\n", TypeText},
+		{"word", "hello", TypeText},
+		{"sentence", "Remember to buy milk on the way home.", TypeText},
+		{"paragraphs", "First paragraph of a letter.\n\nSecond paragraph, with more words.", TypeText},
+		{"prose with braces", "Use {name} in the greeting, then sign it.", TypeText},
+		{"prose with a brace on each line", "Dear {name},\nyour order {id} has shipped.", TypeText},
+		{"prose with dashes", "Shopping - milk - eggs - bread", TypeText},
+		{"prose with a dash list item", "Notes from today\n- call the bank", TypeText},
+		{"prose with a colon", "Reminder: the meeting moved to Friday.", TypeText},
+		{"key value prose", "Note: call me back tomorrow.\nReason: the meeting moved.", TypeText},
+		{"mail headers", "From: Alice Smith\nSubject: Lunch on Friday", TypeText},
+		{"lowercase key value prose", "todo: buy milk and eggs.\nlater: call mom about the weekend!", TypeText},
+		{"time of day", "Lunch at 12:30, dinner at 19:00", TypeText},
+		{"issue number", "#123", TypeText},
+		{"hex word", "deadbeefcafebabe", TypeText},
+		{"identifier", "someVeryLongIdentifierName", TypeText},
+		{"single code hint", "Check that x == y before you go", TypeText},
+		{"command word in a sentence", "find the keys before you leave", TypeText},
+		{"invalid json", "{not: valid, json}", TypeText},
+		{"scheme only", "https://", TypeText},
+		{"mailto prose", "write to me at alice@example.com please", TypeText},
+
+		// URLs
+		{"http url", "https://example.com/path?q=1#top", TypeURL},
+		{"url with spaces around", "  http://localhost:8080/  \n", TypeURL},
+		{"ssh url", "ssh://git@github.com/dvd/cliptui.git", TypeURL},
+		{"git url", "git://example.com/repo.git", TypeURL},
+
+		// Emails
+		{"email", "alice@example.com", TypeEmail},
+		{"mailto", "mailto:bob.smith+tag@mail.example.org", TypeEmail},
+
+		// IPs
+		{"ipv4", "192.168.1.10", TypeIP},
+		{"ipv6", "2001:db8::1", TypeIP},
+		{"cidr", "10.0.0.0/8", TypeIP},
+		{"ip and port", "127.0.0.1:5432", TypeIP},
+		{"ipv6 and port", "[::1]:8080", TypeIP},
+
+		// Colors
+		{"hex color", "#ff8800", TypeColor},
+		{"short hex color", "#f80", TypeColor},
+		{"hex color with alpha", "#FF8800CC", TypeColor},
+		{"rgb", "rgb(255, 136, 0)", TypeColor},
+		{"rgba", "rgba(255,136,0,0.5)", TypeColor},
+		{"hsl", "hsl(33, 100%, 50%)", TypeColor},
+		{"modern rgb", "rgb(255 136 0 / 50%)", TypeColor},
+
+		// UUIDs
+		{"uuid", "123e4567-e89b-12d3-a456-426614174000", TypeUUID},
+		{"upper uuid", "123E4567-E89B-12D3-A456-426614174000", TypeUUID},
+
+		// Paths
+		{"absolute path", "/etc/nginx/nginx.conf", TypePath},
+		{"home path", "~/Documents/report.pdf", TypePath},
+		{"relative path", "./scripts/build.sh", TypePath},
+		{"parent path", "../README.md", TypePath},
+		{"windows path", `C:\Users\alice\Desktop`, TypePath},
+		{"path with a space", "/home/alice/My Documents", TypePath},
+
+		// JSON
+		{"json object", `{"name": "cliptui", "stars": 42}`, TypeJSON},
+		{"json array", `[1, 2, 3]`, TypeJSON},
+		{"nested json", "{\n  \"a\": {\n    \"b\": [true, null]\n  }\n}", TypeJSON},
+		{"empty json object", "{}", TypeJSON},
+
+		// YAML
+		{"flat yaml", "host: localhost\nport: 8080", TypeYAML},
+		{"nested yaml", "server:\n  host: localhost\n  port: 8080", TypeYAML},
+		{"yaml document", "---\nname: cliptui\nversion: 1.0", TypeYAML},
+		{"yaml list of maps", "- name: install\n  run: make install\n- name: test\n  run: make test", TypeYAML},
+		{"github workflow", "name: ci\non:\n  push:\n    branches: [main]\njobs:\n  test:\n    runs-on: ubuntu-latest", TypeYAML},
+		{"kubernetes", "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n  labels:\n    app: web", TypeYAML},
+		{"capitalized nested yaml", "Resources:\n  Bucket:\n    Type: AWS::S3::Bucket", TypeYAML},
+		{"yaml with comments", "# settings\nenabled: true\nretries: 3", TypeYAML},
+
+		// XML and HTML
+		{"xml declaration", `<?xml version="1.0"?><note><to>Tove</to></note>`, TypeXML},
+		{"xml", "<config>\n  <item key=\"a\">1</item>\n</config>", TypeXML},
+		{"html document", "<!DOCTYPE html>\n<html><body><p>Hi</p></body></html>", TypeHTML},
+		{"html fragment", `<div class="card"><span>Hi</span></div>`, TypeHTML},
+		{"not markup", "<- arrow and -> arrow >", TypeText},
+
+		// Shell
+		{"shebang", "#!/bin/bash\necho hi", TypeShell},
+		{"git command", "git commit -m 'fix typo'", TypeShell},
+		{"sudo", "sudo apt install ripgrep", TypeShell},
+		{"prompt", "$ make test", TypeShell},
+		{"pipeline", "cat access.log | grep 404 | wc -l", TypeShell},
+		{"weak command with flag", "ls -la", TypeShell},
+		{"commands", "cd ~/src\nmake build && ./bin/app", TypeShell},
+
+		// Diffs
+		{"git diff", "diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b", TypeDiff},
+		{"unified diff", "--- a/x\n+++ b/x\n@@ -1,2 +1,2 @@\n a\n-b\n+c", TypeDiff},
+		{"bare hunk", "@@ -10,3 +10,4 @@\n context\n+added", TypeDiff},
+
+		// Base64
+		{"padded base64", "SGVsbG8sIFdvcmxkIQ==", TypeBase64},
+		{"long base64", "U29tZSBsb25nZXIgdGV4dCB0aGF0IGlzIGVuY29kZWQ", TypeBase64},
+
+		// Code
+		{"go", "func main() {\n\tfmt.Println(\"hi\")\n}", TypeCode},
+		{"javascript", "const x = () => {\n  return 1;\n};", TypeCode},
+		{"python", "def greet(name):\n    return f\"hi {name}\"\n\nclass A:\n    pass", TypeCode},
+		{"c", "#include <stdio.h>\nint main(void) {\n  return 0;\n}", TypeCode},
+		{"python shebang", "#!/usr/bin/env python3\nprint('hi')", TypeCode},
+		{"rust", "fn main() {\n    let v: Vec<i32> = Vec::new();\n}", TypeCode},
+
+		// Markdown
+		{"headings", "# Title\n\nSome text.\n\n## Section\n\nMore text.", TypeMarkdown},
+		{"fenced code", "Run this:\n\n```\nmake\n```", TypeMarkdown},
+		{"list", "- milk\n- eggs\n- bread", TypeMarkdown},
+		{"links", "See [the docs](https://example.com) and [the code](https://github.com).", TypeMarkdown},
+		{"inline", "This is **important** and `code`.", TypeMarkdown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectType(tt.content); got != tt.want {
+				t.Errorf("DetectType(%q) = %s, want %s", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectCustomTypes(t *testing.T) {
+	SetCustomTypes([]CustomType{
+		{Name: "ticket", Match: func(s string) bool { return len(s) > 5 && s[:5] == "PROJ-" }},
+	})
+	defer SetCustomTypes(nil)
+
+	if got := DetectType("PROJ-123"); got != "ticket" {
+		t.Errorf("DetectType(PROJ-123) = %s, want ticket", got)
+	}
+	// Custom types win over the detectors
+	if got := DetectType("PROJ-https://example.com"); got != "ticket" {
+		t.Errorf("DetectType(PROJ-https://example.com) = %s, want ticket", got)
+	}
+	if got := DetectType("https://example.com"); got != TypeURL {
+		t.Errorf("DetectType(https://example.com) = %s, want url", got)
+	}
+	if !KnownType("ticket") {
+		t.Error("KnownType(ticket) = false, want true")
+	}
+}
+
+func TestDetectConfidence(t *testing.T) {
+	for _, content := range []string{"hello", "Use {name} here", ""} {
+		if d := Detect(content); d.Type != TypeText || d.Confidence != 0 {
+			t.Errorf("Detect(%q) = %+v, want text with no confidence", content, d)
+		}
+	}
+	if d := Detect("#123"); d.Type != TypeText {
+		t.Errorf("Detect(#123) = %+v, want text", d)
+	}
+	if d := Detect(`{"a": 1}`); d.Type != TypeJSON || d.Confidence < minConfidence {
+		t.Errorf("Detect(JSON) = %+v, want a confident json verdict", d)
+	}
+}
@@ -1,8 +1,10 @@
 package types
 
 import (
+	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -13,81 +15,104 @@ const (
 type ClipboardItem struct {
 	ID        int64     `json:"id"`
 	Content   string    `json:"content"`
-	Type      string    `json:"type"` // text, code, markdown, url
+	Type      string    `json:"type"` // one of Types
 	Timestamp time.Time `json:"timestamp"`
 	Preview   string    `json:"preview"` // truncated version for list view
+	Pinned    bool      `json:"pinned"`
+	Tags      []string  `json:"tags,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"` // zero if the item never expires
+	Language  string    `json:"language,omitempty"`  // programming language of code, a chroma lexer name
 }
 
+// Query selects clipboard items, newest first. Zero fields do not filter.
+type Query struct {
+	Limit  int       `json:"limit,omitempty"`
+	Type   string    `json:"type,omitempty"`
+	Since  time.Time `json:"since,omitempty"`
+	Until  time.Time `json:"until,omitempty"`
+	Pinned bool      `json:"pinned,omitempty"` // only pinned items
+	Tag    string    `json:"tag,omitempty"`
+	// Search is a query in the search box syntax, such as "type:url -draft"
+	Search string `json:"search,omitempty"`
+}
+
+// Event describes a change to the clipboard history
+type Event struct {
+	Kind string         `json:"kind"` // added, deleted, updated, cleared
+	ID   int64          `json:"id,omitempty"`
+	Item *ClipboardItem `json:"item,omitempty"`
+}
+
+// EventKind constants
+const (
+	EventAdded   = "added"
+	EventDeleted = "deleted"
+	EventUpdated = "updated"
+	EventCleared = "cleared"
+)
+
 // ItemType constants
 const (
 	TypeText     = "text"
 	TypeCode     = "code"
 	TypeMarkdown = "markdown"
 	TypeURL      = "url"
+	TypeBinary   = "binary"
+	TypeJSON     = "json"
+	TypeYAML     = "yaml"
+	TypeXML      = "xml"
+	TypeHTML     = "html"
+	TypePath     = "path"
+	TypeEmail    = "email"
+	TypeIP       = "ip"
+	TypeColor    = "color"
+	TypeUUID     = "uuid"
+	TypeShell    = "shell"
+	TypeDiff     = "diff"
+	TypeBase64   = "base64"
 )
 
-// DetectType attempts to determine the content type
-func DetectType(content string) string {
-	if len(content) == 0 {
-		return TypeText
-	}
-
-	if isURL(content) {
-		return TypeURL
-	}
-	if isCode(content) {
-		return TypeCode
-	}
-	if isMarkdown(content) {
-		return TypeMarkdown
-	}
-	return TypeText
+// Types lists every item type
+var Types = []string{
+	TypeText, TypeCode, TypeMarkdown, TypeURL, TypeBinary, TypeJSON, TypeYAML,
+	TypeXML, TypeHTML, TypePath, TypeEmail, TypeIP, TypeColor, TypeUUID,
+	TypeShell, TypeDiff, TypeBase64,
 }
 
-func isURL(s string) bool {
-	if len(s) >= maxURLLength {
-		return false
-	}
-	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
-}
+// previewLength is the width in columns of ClipboardItem.Preview, before
+// the ellipsis
+const previewLength = 100
 
-func isCode(s string) bool {
-	codeIndicators := []string{"{", "}", "func ", "def ", "class ", "import ", "package ", "const ", "var ", "let "}
-	for _, indicator := range codeIndicators {
-		if contains(s, indicator) {
-			return true
-		}
+// TruncatePreview creates a preview string of content cut after maxLen
+// columns, between grapheme clusters. The preview starts with the exact
+// bytes of content, so offsets into content can be found in it.
+func TruncatePreview(content string, maxLen int) string {
+	end := cutIndex(content, maxLen)
+	if end == len(content) {
+		return content
 	}
-	return false
+	return content[:end] + ellipsis
 }
 
-func isMarkdown(s string) bool {
-	mdIndicators := []string{"# ", "## ", "- ", "* ", "```", "**", "__"}
-	for _, indicator := range mdIndicators {
-		if contains(s, indicator) {
-			return true
-		}
+// MakePreview builds the list preview of an item
+func MakePreview(content, itemType string) string {
+	if itemType == TypeBinary {
+		return fmt.Sprintf("<binary data, %d bytes>", len(content))
 	}
-	return false
+	return TruncatePreview(content, previewLength)
 }
 
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && findSubstring(s, substr)
+// IsBinary reports whether content looks like binary data rather than text:
+// it contains NUL bytes or is not valid UTF-8
+func IsBinary(content string) bool {
+	return strings.IndexByte(content, 0) >= 0 || !utf8.ValidString(content)
 }
 
-func findSubstring(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
-}
-
-// TruncatePreview creates a preview string
-func TruncatePreview(content string, maxLen int) string {
-	if len(content) <= maxLen {
-		return content
+// ValidateTag checks that a tag can be stored, tags are single words
+// without commas
+func ValidateTag(tag string) error {
+	if tag == "" || strings.ContainsAny(tag, ", \t\n") {
+		return fmt.Errorf("invalid tag %q: tags must be non-empty and contain no commas or whitespace", tag)
 	}
-	return content[:maxLen] + "..."
+	return nil
 }
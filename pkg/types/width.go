@@ -0,0 +1,143 @@
+package types
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// ellipsis ends text that was shortened
+const ellipsis = "..."
+
+// escapeSequence matches a terminal escape sequence at the start of a string:
+// CSI sequences such as colors, OSC sequences such as titles and hyperlinks,
+// and two-byte escapes
+var escapeSequence = regexp.MustCompile(`^\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)?|[@-Z\\-_])`)
+
+// StringWidth returns the number of terminal columns s takes up
+func StringWidth(s string) int {
+	return uniseg.StringWidth(s)
+}
+
+// Truncate shortens s to at most width columns, ending it in "..." when it
+// was cut and there is room for it. It only cuts between grapheme clusters,
+// so accented letters, emoji and wide characters are never split.
+func Truncate(s string, width int) string {
+	if StringWidth(s) <= width {
+		return s
+	}
+	if width < len(ellipsis) {
+		return s[:cutIndex(s, width)]
+	}
+	return s[:cutIndex(s, width-len(ellipsis))] + ellipsis
+}
+
+// Pad appends spaces to s until it is width columns wide, truncating it
+// when it is wider
+func Pad(s string, width int) string {
+	s = Truncate(s, width)
+	return s + strings.Repeat(" ", max(width-StringWidth(s), 0))
+}
+
+// cutIndex returns the byte length of the longest run of whole grapheme
+// clusters at the start of s that fits in width columns. Clusters without
+// width, like line breaks, count as one column so that the run stays short.
+func cutIndex(s string, width int) int {
+	used, end := 0, 0
+	state := -1
+	for rest := s; rest != ""; {
+		var cluster string
+		var w int
+		cluster, rest, w, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		w = max(w, 1)
+		if used+w > width {
+			break
+		}
+		used += w
+		end += len(cluster)
+	}
+	return end
+}
+
+// Sanitize makes s safe to print on a single line of a terminal: line
+// breaks and tabs become spaces, and escape sequences, other control
+// characters and invalid UTF-8 are removed
+func Sanitize(s string) string {
+	clean, _ := sanitize(s, true)
+	return clean
+}
+
+// StripEscapes removes escape sequences, control characters other than line
+// breaks and tabs, and invalid UTF-8 from s, for showing it in a terminal
+// as it would be printed
+func StripEscapes(s string) string {
+	clean, _ := sanitize(s, false)
+	return clean
+}
+
+// SingleLine sanitizes s and truncates it to width columns, for list views
+// that show one item per line
+func SingleLine(s string, width int) string {
+	line, _ := SingleLineOffsets(s, width)
+	return line
+}
+
+// SingleLineOffsets is SingleLine that also returns, for every byte of the
+// line, the byte offset in s it was taken from, or -1 for the ellipsis, so
+// that positions in s can be found in the line
+func SingleLineOffsets(s string, width int) (string, []int) {
+	clean, offsets := sanitize(s, true)
+	if StringWidth(clean) <= width {
+		return clean, offsets
+	}
+
+	if width < len(ellipsis) {
+		end := cutIndex(clean, width)
+		return clean[:end], offsets[:end]
+	}
+	end := cutIndex(clean, width-len(ellipsis))
+	offsets = offsets[:end]
+	for range ellipsis {
+		offsets = append(offsets, -1)
+	}
+	return clean[:end] + ellipsis, offsets
+}
+
+// sanitize implements Sanitize, or StripEscapes unless flatten is set, also
+// returning the source offset of every byte of the result
+func sanitize(s string, flatten bool) (string, []int) {
+	var b strings.Builder
+	b.Grow(len(s))
+	offsets := make([]int, 0, len(s))
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			if loc := escapeSequence.FindStringIndex(s[i:]); loc != nil {
+				i += loc[1]
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\n' || r == '\t' || (r == '\r' && flatten):
+			if flatten {
+				b.WriteByte(' ')
+			} else {
+				b.WriteRune(r)
+			}
+			offsets = append(offsets, i)
+		case r == utf8.RuneError && size == 1, unicode.IsControl(r):
+			// Dropped
+		default:
+			b.WriteString(s[i : i+size])
+			for j := range size {
+				offsets = append(offsets, i+j)
+			}
+		}
+		i += size
+	}
+	return b.String(), offsets
+}
@@ -0,0 +1,187 @@
+package types
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Mixed-script samples and their widths in columns
+const (
+	cjk       = "日本語テキスト"                    // 7 wide characters, 14 columns
+	zwj       = "\U0001f469\u200d\U0001f4bb" // woman, zero width joiner, laptop: 2 columns
+	flag      = "\U0001f1ef\U0001f1f5"       // two regional indicators: 2 columns
+	combining = "e\u0301"                    // e and a combining acute accent: 1 column
+	red       = "\x1b[31mred\x1b[0m"
+)
+
+func TestStringWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"hello", 5},
+		{cjk, 14},
+		{zwj, 2},
+		{flag, 2},
+		{combining, 1},
+		{"a" + zwj + "b" + cjk[:6], 8},
+	}
+	for _, tt := range tests {
+		if got := StringWidth(tt.s); got != tt.want {
+			t.Errorf("StringWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello world", 8, "hello..."},
+		{cjk, 14, cjk},
+		{cjk, 8, "日本..."},
+		// A wide character that would end past the width is left out
+		{cjk, 6, "日..."},
+		{"a" + cjk, 6, "a日..."},
+		{zwj + zwj + zwj, 5, zwj + "..."},
+		{zwj + zwj + zwj, 4, "..."},
+		{flag + flag + flag, 5, flag + "..."},
+		{strings.Repeat(combining, 5), 4, combining + "..."},
+		// Without room for the ellipsis the text is only clipped
+		{"hello", 2, "he"},
+		{cjk, 2, "日"},
+		{cjk, 1, ""},
+		{zwj + "x", 2, zwj},
+		{combining + "x", 1, combining},
+		{"hello", 0, ""},
+		{"hello", -1, ""},
+	}
+
+	for _, tt := range tests {
+		got := Truncate(tt.s, tt.width)
+		if got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+		if w := StringWidth(got); w > max(tt.width, 0) {
+			t.Errorf("Truncate(%q, %d) is %d columns wide", tt.s, tt.width, w)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"hi", 5, "hi   "},
+		{"日本", 6, "日本  "},
+		{zwj, 4, zwj + "  "},
+		{combining, 3, combining + "  "},
+		// Cut to "日..." which is 5 columns, then padded
+		{cjk, 6, "日... "},
+		{"hello", 2, "he"},
+		{cjk, 3, "..."},
+		{cjk, 2, "日"},
+		{cjk, 1, " "},
+		{"", 0, ""},
+	}
+
+	for _, tt := range tests {
+		got := Pad(tt.s, tt.width)
+		if got != tt.want {
+			t.Errorf("Pad(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+		if w := StringWidth(got); w != tt.width {
+			t.Errorf("Pad(%q, %d) is %d columns wide", tt.s, tt.width, w)
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"plain", "plain"},
+		{red + " text", "red text"},
+		{"a\tb\nc\r\nd", "a b c  d"},
+		{"\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"\x1b]0;title\x07after", "after"},
+		{"\x1bMup", "up"},
+		{"日本\x07語\x00", "日本語"},
+		{"bad\xffbyte", "badbyte"},
+		{combining + " " + zwj + " " + flag, combining + " " + zwj + " " + flag},
+		{"\x1b[1m" + cjk + "\x1b[0m", cjk},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.s); got != tt.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestStripEscapes(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{red + "\ttext\n", "red\ttext\n"},
+		{"a\r\nb", "a\nb"},
+		{"日本\x1b[2J語", "日本語"},
+	}
+	for _, tt := range tests {
+		if got := StripEscapes(tt.s); got != tt.want {
+			t.Errorf("StripEscapes(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestSingleLineOffsets(t *testing.T) {
+	tests := []struct {
+		s       string
+		width   int
+		want    string
+		offsets []int
+	}{
+		{"a\tb", 10, "a b", []int{0, 1, 2}},
+		// The escape sequences take 4 bytes each
+		{"\x1b[1m日本\x1b[0m語", 10, "日本語", []int{4, 5, 6, 7, 8, 9, 14, 15, 16}},
+		{cjk, 7, "日本...", []int{0, 1, 2, 3, 4, 5, -1, -1, -1}},
+		{cjk, 2, "日", []int{0, 1, 2}},
+		{combining + "x", 10, combining + "x", []int{0, 1, 2, 3}},
+		{zwj + "\n" + zwj, 5, zwj + " " + zwj, []int{
+			0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10,
+			11,
+			12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22,
+		}},
+		{"x\xffy" + red, 5, "xyred", []int{0, 2, 8, 9, 10}},
+		{"x\xffy" + red, 4, "x...", []int{0, -1, -1, -1}},
+		{"x\xffy" + red, 2, "xy", []int{0, 2}},
+	}
+
+	for _, tt := range tests {
+		line, offsets := SingleLineOffsets(tt.s, tt.width)
+		if line != tt.want {
+			t.Errorf("SingleLineOffsets(%q, %d) = %q, want %q", tt.s, tt.width, line, tt.want)
+			continue
+		}
+		if len(offsets) != len(line) {
+			t.Errorf("SingleLineOffsets(%q, %d) has %d offsets for %d bytes", tt.s, tt.width, len(offsets), len(line))
+			continue
+		}
+		if tt.offsets != nil && !slices.Equal(offsets, tt.offsets) {
+			t.Errorf("SingleLineOffsets(%q, %d) offsets = %v, want %v", tt.s, tt.width, offsets, tt.offsets)
+		}
+		// Every byte that is not the ellipsis was copied from its offset
+		for i, off := range offsets {
+			if off >= 0 && tt.s[off] != line[i] && line[i] != ' ' {
+				t.Errorf("SingleLineOffsets(%q, %d) byte %d is %q, from %q at %d", tt.s, tt.width, i, line[i], tt.s[off], off)
+			}
+		}
+	}
+}